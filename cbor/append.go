@@ -0,0 +1,108 @@
+package cbor
+
+// Append encodes `v` in CBOR wire format and appends it to `dst`,
+// growing the slice as needed, then returns the extended slice.
+// Modeled on the append idiom added to encoding/binary in Go 1.23, it
+// lets callers building streaming/framed protocols reuse one backing
+// array across many messages instead of pre-sizing a scratch buffer
+// for every encode call.
+func Append(dst []byte, v interface{}) []byte {
+	switch item := v.(type) {
+	case nil:
+		return appendFixed(dst, encodeNull)
+	case bool:
+		if item {
+			return appendFixed(dst, encodeTrue)
+		}
+		return appendFixed(dst, encodeFalse)
+	case uint8:
+		return AppendUint(dst, uint64(item))
+	case uint16:
+		return AppendUint(dst, uint64(item))
+	case uint32:
+		return AppendUint(dst, uint64(item))
+	case uint64:
+		return AppendUint(dst, item)
+	case uint:
+		return AppendUint(dst, uint64(item))
+	case int8:
+		return AppendInt(dst, int64(item))
+	case int16:
+		return AppendInt(dst, int64(item))
+	case int32:
+		return AppendInt(dst, int64(item))
+	case int64:
+		return AppendInt(dst, item)
+	case int:
+		return AppendInt(dst, int64(item))
+	case float32:
+		return appendSized(dst, 5, func(buf []byte) int { return encodeFloat32(item, buf) })
+	case float64:
+		return appendSized(dst, 9, func(buf []byte) int { return encodeFloat64(item, buf) })
+	case []byte:
+		return AppendBytes(dst, item)
+	case string:
+		return AppendText(dst, item)
+	}
+	panic(ErrorUnknownType)
+}
+
+// AppendUint appends `v` as a CBOR unsigned integer (major type 0).
+func AppendUint(dst []byte, v uint64) []byte {
+	return appendSized(dst, 9, func(buf []byte) int { return encodeUint64(v, buf) })
+}
+
+// AppendInt appends `v` as a CBOR integer, using major type 0 for
+// non-negative values and major type 1 otherwise.
+func AppendInt(dst []byte, v int64) []byte {
+	return appendSized(dst, 9, func(buf []byte) int { return encodeInt64(v, buf) })
+}
+
+// AppendText appends `s` as a CBOR text string (major type 3).
+func AppendText(dst []byte, s string) []byte {
+	return appendSized(dst, 9+len(s), func(buf []byte) int { return encodeText(s, buf) })
+}
+
+// AppendBytes appends `b` as a CBOR byte string (major type 2).
+func AppendBytes(dst []byte, b []byte) []byte {
+	return appendSized(dst, 9+len(b), func(buf []byte) int { return encodeBytes(b, buf) })
+}
+
+// AppendArrayStart appends the header of an indefinite-length array.
+// Terminate it with AppendBreak.
+func AppendArrayStart(dst []byte) []byte {
+	return appendFixed(dst, encodeArrayStart)
+}
+
+// AppendMapStart appends the header of an indefinite-length map.
+// Terminate it with AppendBreak.
+func AppendMapStart(dst []byte) []byte {
+	return appendFixed(dst, encodeMapStart)
+}
+
+// AppendBreak appends the break-stop code that closes an
+// indefinite-length array, map, byte-string or text-string.
+func AppendBreak(dst []byte) []byte {
+	return appendFixed(dst, encodeBreakStop)
+}
+
+// AppendTag appends a CBOR tag header (major type 6) for tag number
+// `num`. The tagged item itself must be appended immediately after.
+func AppendTag(dst []byte, num uint64) []byte {
+	return appendSized(dst, 9, func(buf []byte) int { return encodeTag(num, buf) })
+}
+
+// appendFixed grows dst by exactly one byte and writes `enc` into it;
+// for the fixed-width simple-type/break-stop encoders.
+func appendFixed(dst []byte, enc func([]byte) int) []byte {
+	return appendSized(dst, 1, enc)
+}
+
+// appendSized grows dst by `maxLen` scratch bytes, lets `enc` write
+// into that tail, then trims to the number of bytes actually used.
+func appendSized(dst []byte, maxLen int, enc func([]byte) int) []byte {
+	base := len(dst)
+	dst = append(dst, make([]byte, maxLen)...)
+	n := enc(dst[base:])
+	return dst[:base+n]
+}
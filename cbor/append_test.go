@@ -0,0 +1,98 @@
+package cbor
+
+import "testing"
+
+func TestAppendScalarsRoundTrip(t *testing.T) {
+	var dst []byte
+	dst = Append(dst, nil)
+	dst = Append(dst, true)
+	dst = Append(dst, false)
+	dst = Append(dst, int64(-5))
+	dst = Append(dst, uint64(5))
+	dst = Append(dst, "hi")
+	dst = Append(dst, []byte{1, 2, 3})
+
+	want := []interface{}{nil, true, false, int64(-5), uint64(5), "hi", []byte{1, 2, 3}}
+	off := 0
+	for i, w := range want {
+		got, n := decode(dst[off:])
+		off += n
+		if !valuesEqualForAppendTest(got, w) {
+			t.Fatalf("item[%d] = %v, want %v", i, got, w)
+		}
+	}
+	if off != len(dst) {
+		t.Fatalf("decoded %d bytes, dst has %d", off, len(dst))
+	}
+}
+
+func TestAppendGrowsSharedBackingArray(t *testing.T) {
+	dst := make([]byte, 0, 1) // deliberately undersized, forces growth
+	dst = AppendUint(dst, 42)
+	dst = AppendText(dst, "hello world this is long enough to force a grow")
+	dst = AppendBytes(dst, []byte("more bytes appended after growth"))
+
+	got, n := decode(dst)
+	if got != uint64(42) {
+		t.Fatalf("first item = %v, want 42", got)
+	}
+	got, n2 := decode(dst[n:])
+	if got != "hello world this is long enough to force a grow" {
+		t.Fatalf("second item = %v", got)
+	}
+	got, _ = decode(dst[n+n2:])
+	if s, ok := got.([]byte); !ok || string(s) != "more bytes appended after growth" {
+		t.Fatalf("third item = %v", got)
+	}
+}
+
+func TestAppendIndefiniteArray(t *testing.T) {
+	var dst []byte
+	dst = AppendArrayStart(dst)
+	dst = AppendInt(dst, 1)
+	dst = AppendInt(dst, 2)
+	dst = AppendBreak(dst)
+
+	got, _ := decode(dst)
+	arr, ok := got.([]interface{})
+	if !ok || len(arr) != 2 || arr[0] != uint64(1) || arr[1] != uint64(2) {
+		t.Fatalf("decode = %v", got)
+	}
+}
+
+func TestAppendTag(t *testing.T) {
+	var dst []byte
+	dst = AppendTag(dst, 37)
+	dst = AppendBytes(dst, make([]byte, 16))
+
+	got, _ := decode(dst)
+	tagged, ok := got.(RawTag)
+	if !ok || tagged.Number != 37 {
+		t.Fatalf("decode = %v, want RawTag{Number: 37}", got)
+	}
+}
+
+func TestAppendPanicsOnUnknownType(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Append(unsupported type) did not panic")
+		}
+	}()
+	Append(nil, struct{}{})
+}
+
+func valuesEqualForAppendTest(a, b interface{}) bool {
+	if ab, ok := a.([]byte); ok {
+		bb, ok := b.([]byte)
+		if !ok || len(ab) != len(bb) {
+			return false
+		}
+		for i := range ab {
+			if ab[i] != bb[i] {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
@@ -17,9 +17,6 @@
 package cbor
 
 import "math"
-import "math/big"
-import "regexp"
-import "time"
 import "errors"
 import "encoding/binary"
 
@@ -368,27 +365,16 @@ func encode(item interface{}, out []byte) int {
 	// simple types
 	case Undefined:
 		n += encodeUndefined(out)
-	// tagged encoding
-	case time.Time: // tag-0
-		n += encodeDateTime(v, out)
-	case Epoch: // tag-1
-		n += encodeDateTime(v, out)
-	case EpochMicro: // tag-1
-		n += encodeDateTime(v, out)
-	case *big.Int:
-		n += encodeBigNum(v, out)
-	case DecimalFraction:
-		n += encodeDecimalFraction(v, out)
-	case BigFloat:
-		n += encodeBigFloat(v, out)
-	case Cbor:
-		n += encodeCbor(v, out)
-	case *regexp.Regexp:
-		n += encodeRegexp(v, out)
-	case CborPrefix:
-		n += encodeCborPrefix(v, out)
 	default:
-		panic(ErrorUnknownType)
+		// tagged encoding: date-time, epoch, bignum, decimal fraction,
+		// bigfloat, embedded CBOR, regexp, self-describe prefix, and
+		// anything an application registered with RegisterTag.
+		if entry, ok := tagEncoderFor(item); ok {
+			h := encodeTagHeader(entry.num, out)
+			n += h + entry.enc(item, out[h:])
+		} else {
+			n += encodeReflect(item, out)
+		}
 	}
 	return n
 }
@@ -0,0 +1,253 @@
+package cbor
+
+import "encoding/base64"
+import "encoding/hex"
+import "encoding/json"
+import "errors"
+import "math"
+import "math/big"
+import "sort"
+import "strconv"
+
+// ErrorJSONMapKey is returned by ToJSON when a CBOR map key cannot be
+// coerced into a JSON object key (a string).
+var ErrorJSONMapKey = errors.New("cbor.jsonMapKey")
+
+// tag numbers RFC 8949 §3.4.5 and §6.1 use to hint how a byte string
+// enclosed in the tagged item should be rendered as JSON text.
+const (
+	tagExpectedBase64URL uint64 = 21
+	tagExpectedBase64    uint64 = 22
+	tagExpectedBase16    uint64 = 23
+)
+
+// ToJSON converts the single CBOR data-item encoded in `cborBytes`
+// into its RFC 8949 §6 JSON rendering, appending the UTF-8 text into
+// `jsonBuf` (which must be large enough) and returning the number of
+// bytes written. Byte strings render as unpadded base64url text
+// unless wrapped in tag 21/22/23, which select base64url, base64 or
+// base16 respectively; bignums (tag 2/3) render as a JSON number
+// string; non-finite floats render as null; map keys are coerced to
+// strings. Indefinite-length arrays and maps render like definite
+// ones; indefinite-length byte and text strings are not concatenated,
+// since decode() returns only their Indefinite marker.
+func ToJSON(cborBytes []byte, jsonBuf []byte) (int, error) {
+	item, _ := decode(cborBytes)
+	return marshalJSON(item, jsonBuf)
+}
+
+// FromJSON converts the single JSON value encoded in `jsonBytes` into
+// CBOR, appending it into `cborBuf` (which must be large enough) and
+// returning the number of bytes written. JSON numbers that parse as
+// an integral value are encoded as a CBOR integer; all others fall
+// back to a CBOR float, per this package's existing json<->cbor
+// convention. Object members are encoded in sorted key order.
+func FromJSON(jsonBytes []byte, cborBuf []byte) (int, error) {
+	var value interface{}
+	if err := json.Unmarshal(jsonBytes, &value); err != nil {
+		return 0, err
+	}
+	return encode(fromJSONValue(value), cborBuf), nil
+}
+
+func fromJSONValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		pairs := make([][2]interface{}, 0, len(v))
+		for _, key := range keys {
+			pairs = append(pairs, [2]interface{}{key, fromJSONValue(v[key])})
+		}
+		return pairs
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, val := range v {
+			items[i] = fromJSONValue(val)
+		}
+		return items
+	case float64:
+		if i := int64(v); float64(i) == v {
+			return i
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+func marshalJSON(item interface{}, out []byte) (int, error) {
+	switch v := item.(type) {
+	case nil:
+		return copy(out, "null"), nil
+
+	case bool:
+		if v {
+			return copy(out, "true"), nil
+		}
+		return copy(out, "false"), nil
+
+	case int64:
+		return copy(out, strconv.FormatInt(v, 10)), nil
+
+	case uint64:
+		return copy(out, strconv.FormatUint(v, 10)), nil
+
+	case float32:
+		return marshalJSONFloat(float64(v), out)
+
+	case float64:
+		return marshalJSONFloat(v, out)
+
+	case string:
+		return marshalJSONString(v, out)
+
+	case []byte:
+		return marshalJSONBytes(v, tagExpectedBase64URL, out)
+
+	case RawTag:
+		return marshalJSONTag(v, out)
+
+	case *big.Int:
+		return marshalJSONString(v.String(), out)
+
+	case []interface{}:
+		return marshalJSONArray(v, out)
+
+	case [][2]interface{}:
+		return marshalJSONMap(v, out)
+
+	default:
+		return marshalJSONReflect(v, out)
+	}
+}
+
+// marshalJSONTag applies the byte-string encoding hint of tag
+// 21/22/23 and, for any other tag, converts the tagged value with
+// the tag number itself dropped, per RFC 8949 §6.1.
+func marshalJSONTag(tag RawTag, out []byte) (int, error) {
+	switch tag.Number {
+	case tagExpectedBase64URL:
+		if b, ok := tag.Value.([]byte); ok {
+			return marshalJSONBytes(b, tagExpectedBase64URL, out)
+		}
+	case tagExpectedBase64:
+		if b, ok := tag.Value.([]byte); ok {
+			return marshalJSONBytes(b, tagExpectedBase64, out)
+		}
+	case tagExpectedBase16:
+		if b, ok := tag.Value.([]byte); ok {
+			return marshalJSONBytes(b, tagExpectedBase16, out)
+		}
+	}
+	return marshalJSON(tag.Value, out)
+}
+
+func marshalJSONBytes(b []byte, hint uint64, out []byte) (int, error) {
+	switch hint {
+	case tagExpectedBase64:
+		return marshalJSONString(base64.StdEncoding.EncodeToString(b), out)
+	case tagExpectedBase16:
+		return marshalJSONString(hex.EncodeToString(b), out)
+	default:
+		return marshalJSONString(base64.RawURLEncoding.EncodeToString(b), out)
+	}
+}
+
+func marshalJSONFloat(f float64, out []byte) (int, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return copy(out, "null"), nil
+	}
+	return copy(out, strconv.FormatFloat(f, 'g', -1, 64)), nil
+}
+
+func marshalJSONString(s string, out []byte) (int, error) {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return 0, err
+	}
+	return copy(out, b), nil
+}
+
+func marshalJSONArray(items []interface{}, out []byte) (int, error) {
+	n := copy(out, "[")
+	for i, item := range items {
+		if i > 0 {
+			n += copy(out[n:], ",")
+		}
+		m, err := marshalJSON(item, out[n:])
+		if err != nil {
+			return 0, err
+		}
+		n += m
+	}
+	n += copy(out[n:], "]")
+	return n, nil
+}
+
+func marshalJSONMap(pairs [][2]interface{}, out []byte) (int, error) {
+	n := copy(out, "{")
+	for i, pair := range pairs {
+		if i > 0 {
+			n += copy(out[n:], ",")
+		}
+		key, err := jsonMapKey(pair[0])
+		if err != nil {
+			return 0, err
+		}
+		m, err := marshalJSONString(key, out[n:])
+		if err != nil {
+			return 0, err
+		}
+		n += m
+		n += copy(out[n:], ":")
+		m, err = marshalJSON(pair[1], out[n:])
+		if err != nil {
+			return 0, err
+		}
+		n += m
+	}
+	n += copy(out[n:], "}")
+	return n, nil
+}
+
+// jsonMapKey coerces a decoded CBOR map key into the JSON object key
+// string RFC 8949 §6.1 requires: text keys pass through unchanged;
+// numeric and boolean keys (the COSE/CWT norm) render as their natural
+// text form; anything else (nested arrays/maps, byte strings) has no
+// reasonable text rendering and is rejected.
+func jsonMapKey(key interface{}) (string, error) {
+	switch k := key.(type) {
+	case string:
+		return k, nil
+	case int64:
+		return strconv.FormatInt(k, 10), nil
+	case uint64:
+		return strconv.FormatUint(k, 10), nil
+	case float32:
+		return strconv.FormatFloat(float64(k), 'g', -1, 32), nil
+	case float64:
+		return strconv.FormatFloat(k, 'g', -1, 64), nil
+	case bool:
+		if k {
+			return "true", nil
+		}
+		return "false", nil
+	}
+	return "", ErrorJSONMapKey
+}
+
+// marshalJSONReflect renders any value this package doesn't otherwise
+// know how to convert (Epoch, time.Time, a registered tag type, ...)
+// through encoding/json, which at minimum round-trips the package's
+// own exported scalar-shaped types.
+func marshalJSONReflect(v interface{}, out []byte) (int, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return 0, err
+	}
+	return copy(out, b), nil
+}
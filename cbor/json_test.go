@@ -0,0 +1,105 @@
+package cbor
+
+import "testing"
+
+func TestToJSONScalars(t *testing.T) {
+	tests := []struct {
+		name string
+		cbor []byte
+		want string
+	}{
+		{"null", []byte{0xf6}, "null"},
+		{"true", []byte{0xf5}, "true"},
+		{"uint", []byte{0x05}, "5"},
+		{"negint", []byte{0x29}, "-10"},
+		{"string", []byte{0x62, 'h', 'i'}, `"hi"`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			out := make([]byte, 64)
+			n, err := ToJSON(tc.cbor, out)
+			if err != nil {
+				t.Fatalf("ToJSON: %v", err)
+			}
+			if got := string(out[:n]); got != tc.want {
+				t.Fatalf("ToJSON(%x) = %q, want %q", tc.cbor, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestToJSONBytesDefaultBase64URL(t *testing.T) {
+	b := []byte{0xff, 0xee}
+	cb := make([]byte, 32)
+	n := encode(b, cb)
+
+	out := make([]byte, 64)
+	m, err := ToJSON(cb[:n], out)
+	if err != nil {
+		t.Fatalf("ToJSON: %v", err)
+	}
+	if got := string(out[:m]); got != `"_-4"` {
+		t.Fatalf("ToJSON([]byte) = %s, want base64url text", got)
+	}
+}
+
+func TestToJSONMapCoercesNonStringKeys(t *testing.T) {
+	pairs := [][2]interface{}{{int64(1), "a"}, {true, "b"}}
+	cb := make([]byte, 64)
+	n := encode(pairs, cb)
+
+	out := make([]byte, 128)
+	m, err := ToJSON(cb[:n], out)
+	if err != nil {
+		t.Fatalf("ToJSON: %v", err)
+	}
+	want := `{"1":"a","true":"b"}`
+	if got := string(out[:m]); got != want {
+		t.Fatalf("ToJSON(map) = %s, want %s", got, want)
+	}
+}
+
+func TestToJSONNonFiniteFloatRendersNull(t *testing.T) {
+	out := make([]byte, 16)
+	n, err := ToJSON([]byte{0xfa, 0x7f, 0xc0, 0, 0}, out) // float32 NaN
+	if err != nil {
+		t.Fatalf("ToJSON: %v", err)
+	}
+	if got := string(out[:n]); got != "null" {
+		t.Fatalf("ToJSON(NaN) = %q, want null", got)
+	}
+}
+
+func TestFromJSONRoundTrip(t *testing.T) {
+	in := `{"a":1,"b":[true,null,"x"],"c":1.5}`
+	cb := make([]byte, 256)
+	n, err := FromJSON([]byte(in), cb)
+	if err != nil {
+		t.Fatalf("FromJSON: %v", err)
+	}
+
+	out := make([]byte, 256)
+	m, err := ToJSON(cb[:n], out)
+	if err != nil {
+		t.Fatalf("ToJSON: %v", err)
+	}
+	want := `{"a":1,"b":[true,null,"x"],"c":1.5}`
+	if got := string(out[:m]); got != want {
+		t.Fatalf("round-trip = %s, want %s", got, want)
+	}
+}
+
+func TestToJSONTag23RendersBase16(t *testing.T) {
+	var dst []byte
+	dst = AppendTag(dst, tagExpectedBase16)
+	dst = AppendBytes(dst, []byte{0xde, 0xad})
+
+	out := make([]byte, 32)
+	n, err := ToJSON(dst, out)
+	if err != nil {
+		t.Fatalf("ToJSON: %v", err)
+	}
+	if got := string(out[:n]); got != `"dead"` {
+		t.Fatalf("ToJSON(tag23) = %s, want \"dead\"", got)
+	}
+}
@@ -0,0 +1,218 @@
+package cbor
+
+import "math"
+import "sort"
+
+// SortMode controls how [][2]interface{} map keys are ordered when
+// encoding through an EncOptions bundle.
+type SortMode byte
+
+const (
+	// SortNone preserves insertion order (the package default).
+	SortNone SortMode = iota
+
+	// SortLengthFirst sorts keys by their encoded length first and
+	// bytewise within a length, per RFC 7049 §3.9 canonical CBOR.
+	SortLengthFirst
+
+	// SortBytewiseLexical sorts keys by their raw encoded bytes,
+	// per RFC 8949 §4.2.1 core deterministic encoding (also used by
+	// CTAP2 canonical CBOR).
+	SortBytewiseLexical
+)
+
+// IntegerMode controls integer-width selection. ShortestInt is
+// currently the only mode: this package's encodeUint64/encodeInt64
+// already always choose the shortest form, so IntegerMode exists to
+// make that guarantee an explicit, documented part of EncOptions
+// rather than an implementation detail.
+type IntegerMode byte
+
+const (
+	// ShortestInt always emits the shortest well-formed integer
+	// encoding for the value (the package's existing behaviour).
+	ShortestInt IntegerMode = iota
+)
+
+// FloatMode controls float-width selection.
+type FloatMode byte
+
+const (
+	// NaturalFloat encodes float32 as flt32 and float64 as flt64,
+	// the package's existing behaviour.
+	NaturalFloat FloatMode = iota
+
+	// ShortestFloat16 downcasts a float to the shortest of
+	// flt16/flt32/flt64 that represents it losslessly, the profile
+	// required for byte-for-byte reproducible signing input (COSE,
+	// CWT, WebAuthn attestation).
+	ShortestFloat16
+)
+
+// EncOptions bundles the settings that make CBOR output
+// reproducible across encoders, for callers who intend to hash or
+// sign the result.
+type EncOptions struct {
+	Sort   SortMode
+	IntMode IntegerMode
+	FloatMode FloatMode
+}
+
+// CanonicalOptions returns the RFC 7049 canonical CBOR profile:
+// length-first key sort, shortest integers, natural-width floats.
+func CanonicalOptions() EncOptions {
+	return EncOptions{Sort: SortLengthFirst, IntMode: ShortestInt, FloatMode: NaturalFloat}
+}
+
+// DeterministicOptions returns the RFC 8949 §4.2 / CTAP2 core
+// deterministic profile: bytewise-lexical key sort, shortest integers,
+// shortest lossless floats.
+func DeterministicOptions() EncOptions {
+	return EncOptions{Sort: SortBytewiseLexical, IntMode: ShortestInt, FloatMode: ShortestFloat16}
+}
+
+// Encode encodes `v` into `out` according to these options, in place
+// of the package-level encode dispatch's natural-width, insertion-order
+// defaults.
+func (opts EncOptions) Encode(v interface{}, out []byte) int {
+	switch item := v.(type) {
+	case [][2]interface{}:
+		return opts.encodeMap(item, out)
+	case []interface{}:
+		return opts.encodeArray(item, out)
+	case float32:
+		return opts.encodeFloat(float64(item), out)
+	case float64:
+		return opts.encodeFloat(item, out)
+	default:
+		return encode(v, out)
+	}
+}
+
+func (opts EncOptions) encodeArray(items []interface{}, out []byte) int {
+	n := encodeUint64(uint64(len(items)), out)
+	out[0] = (out[0] & 0x1f) | type4
+	for _, item := range items {
+		n += opts.Encode(item, out[n:])
+	}
+	return n
+}
+
+func (opts EncOptions) encodeMap(pairs [][2]interface{}, out []byte) int {
+	if opts.Sort == SortNone {
+		n := encodeUint64(uint64(len(pairs)), out)
+		out[0] = (out[0] & 0x1f) | type5
+		for _, kv := range pairs {
+			n += opts.Encode(kv[0], out[n:])
+			n += opts.Encode(kv[1], out[n:])
+		}
+		return n
+	}
+
+	type kv struct {
+		key   []byte
+		value interface{}
+	}
+	scratch := make([]kv, len(pairs))
+	for i, pair := range pairs {
+		scratch[i] = kv{key: opts.encodeKey(pair[0]), value: pair[1]}
+	}
+	less := bytewiseLessCbor
+	if opts.Sort == SortLengthFirst {
+		less = lengthFirstLessCbor
+	}
+	sort.Slice(scratch, func(i, j int) bool { return less(scratch[i].key, scratch[j].key) })
+
+	n := encodeUint64(uint64(len(pairs)), out)
+	out[0] = (out[0] & 0x1f) | type5
+	for _, item := range scratch {
+		n += copy(out[n:], item.key)
+		n += opts.Encode(item.value, out[n:])
+	}
+	return n
+}
+
+// encodeKey encodes a map key into a freshly sized buffer, doubling
+// and retrying when a key's encoding (an arbitrarily long string, or a
+// deeply nested key) doesn't fit a first guess, rather than risking a
+// fixed-size scratch buffer overflowing.
+func (opts EncOptions) encodeKey(item interface{}) []byte {
+	for size := 512; ; size *= 2 {
+		buf := make([]byte, size)
+		if n, ok := opts.tryEncode(item, buf); ok {
+			return buf[:n]
+		}
+	}
+}
+
+func (opts EncOptions) tryEncode(item interface{}, buf []byte) (n int, ok bool) {
+	defer func() {
+		if recover() != nil {
+			n, ok = 0, false
+		}
+	}()
+	return opts.Encode(item, buf), true
+}
+
+func (opts EncOptions) encodeFloat(f float64, out []byte) int {
+	if opts.FloatMode != ShortestFloat16 {
+		return encodeFloat64(f, out)
+	}
+	if math.IsNaN(f) {
+		out[0] = hdr(type7, flt16)
+		out[1], out[2] = 0x7e, 0x00
+		return 3
+	}
+	if f32 := float32(f); float64(f32) == f {
+		if half, ok := shortestFloat16(f32); ok {
+			out[0] = hdr(type7, flt16)
+			out[1], out[2] = byte(half>>8), byte(half)
+			return 3
+		}
+		return encodeFloat32(f32, out)
+	}
+	return encodeFloat64(f, out)
+}
+
+// shortestFloat16 converts an exactly representable float32 into IEEE
+// 754 half-precision, reporting false when precision would be lost.
+// Zero and infinity (NaN is handled by the caller) always survive the
+// conversion regardless of the normal-range check below.
+func shortestFloat16(f float32) (uint16, bool) {
+	bits := math.Float32bits(f)
+	sign := uint16((bits >> 16) & 0x8000)
+	rawExp := (bits >> 23) & 0xff
+	mant := bits & 0x7fffff
+
+	if rawExp == 0 && mant == 0 {
+		return sign, true // +-0
+	}
+	if rawExp == 0xff && mant == 0 {
+		return sign | uint16(0x1f<<10), true // +-Inf
+	}
+
+	exp := int32(rawExp) - 127 + 15
+	if exp <= 0 || exp >= 31 {
+		return 0, false
+	}
+	if mant&0x1fff != 0 {
+		return 0, false
+	}
+	return sign | uint16(exp<<10) | uint16(mant>>13), true
+}
+
+func bytewiseLessCbor(a, b []byte) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return len(a) < len(b)
+}
+
+func lengthFirstLessCbor(a, b []byte) bool {
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	return bytewiseLessCbor(a, b)
+}
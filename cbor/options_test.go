@@ -0,0 +1,69 @@
+package cbor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCanonicalOptionsSortsKeysLengthFirst(t *testing.T) {
+	pairs := [][2]interface{}{
+		{"bb", int64(1)},
+		{"a", int64(2)},
+		{"c", int64(3)},
+	}
+	out := make([]byte, 256)
+	n := CanonicalOptions().Encode(pairs, out)
+
+	got, _ := decode(out[:n])
+	sorted, ok := got.([][2]interface{})
+	if !ok || len(sorted) != 3 {
+		t.Fatalf("decode = %v", got)
+	}
+	want := []string{"a", "c", "bb"}
+	for i, key := range want {
+		if sorted[i][0] != key {
+			t.Fatalf("key[%d] = %v, want %v", i, sorted[i][0], key)
+		}
+	}
+}
+
+func TestDeterministicOptionsDowncastsFloats(t *testing.T) {
+	out := make([]byte, 16)
+	n := DeterministicOptions().Encode(1.0, out)
+	if n != 3 {
+		t.Fatalf("Encode(1.0) took %d bytes, want 3 (flt16)", n)
+	}
+	if major(out[0]) != type7 || info(out[0]) != flt16 {
+		t.Fatalf("Encode(1.0) header = %#x, want flt16", out[0])
+	}
+}
+
+func TestDeterministicOptionsKeyBufferGrowsPastDefaultScratch(t *testing.T) {
+	longKey := strings.Repeat("k", 600) // exceeds the old fixed 512-byte scratch
+	pairs := [][2]interface{}{{longKey, int64(1)}, {"a", int64(2)}}
+	out := make([]byte, 2048)
+
+	n := DeterministicOptions().Encode(pairs, out) // must not panic
+
+	got, _ := decode(out[:n])
+	sorted, ok := got.([][2]interface{})
+	if !ok || len(sorted) != 2 {
+		t.Fatalf("decode = %v", got)
+	}
+	if sorted[0][0] != "a" || sorted[1][0] != longKey {
+		t.Fatalf("sorted keys = %v, %v", sorted[0][0], sorted[1][0])
+	}
+}
+
+func TestEncOptionsSortNonePreservesInsertionOrder(t *testing.T) {
+	pairs := [][2]interface{}{{"z", int64(1)}, {"a", int64(2)}}
+	out := make([]byte, 256)
+	opts := EncOptions{Sort: SortNone}
+	n := opts.Encode(pairs, out)
+
+	got, _ := decode(out[:n])
+	sorted, ok := got.([][2]interface{})
+	if !ok || len(sorted) != 2 || sorted[0][0] != "z" || sorted[1][0] != "a" {
+		t.Fatalf("decode = %v, want insertion order preserved", got)
+	}
+}
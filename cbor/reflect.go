@@ -0,0 +1,229 @@
+package cbor
+
+import "reflect"
+import "strconv"
+import "strings"
+import "sync"
+
+// structField describes one exported field's CBOR encoding, resolved
+// once per Go type from its `cbor:"..."` struct tag and cached for
+// every subsequent encode/decode of that type — the reflect walk over
+// a type's fields happens exactly once, the same caching shape used by
+// cilium/ebpf's sysenc struct-size cache.
+type structField struct {
+	index     []int
+	key       interface{} // string name, or int64/uint64 for keyasint
+	omitempty bool
+}
+
+var structFieldCache sync.Map // map[reflect.Type][]structField
+
+// fieldsOf returns the cached, tag-resolved field list for `t`,
+// building and storing it on the first request for that type.
+func fieldsOf(t reflect.Type) []structField {
+	if cached, ok := structFieldCache.Load(t); ok {
+		return cached.([]structField)
+	}
+	fields := make([]structField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" { // unexported
+			continue
+		}
+		tag := sf.Tag.Get("cbor")
+		if tag == "-" {
+			continue
+		}
+		name, opts := parseTag(tag)
+		field := structField{index: sf.Index}
+		switch {
+		case opts["keyasint"]:
+			key, err := strconv.ParseInt(name, 10, 64)
+			if err != nil {
+				panic(ErrorUnknownType)
+			}
+			field.key = key
+		case name != "":
+			field.key = name
+		default:
+			field.key = sf.Name
+		}
+		field.omitempty = opts["omitempty"]
+		fields = append(fields, field)
+	}
+	structFieldCache.Store(t, fields)
+	return fields
+}
+
+// parseTag splits a `cbor:"name,opt1,opt2"` tag into its name and a
+// set of recognised options.
+func parseTag(tag string) (string, map[string]bool) {
+	parts := strings.Split(tag, ",")
+	opts := make(map[string]bool)
+	for _, opt := range parts[1:] {
+		opts[opt] = true
+	}
+	if len(parts) == 0 {
+		return "", opts
+	}
+	return parts[0], opts
+}
+
+// encodeReflect encodes an arbitrary struct (or pointer to struct) as
+// a CBOR map, honouring `cbor` struct tags, and a slice as a CBOR array
+// of its elements. Anything else falls through to the
+// ErrorUnknownType panic the fixed type-switch already used for
+// unsupported inputs.
+func encodeReflect(item interface{}, out []byte) int {
+	rv := reflect.ValueOf(item)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return encodeNull(out)
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() == reflect.Slice {
+		items := make([]interface{}, rv.Len())
+		for i := range items {
+			items[i] = rv.Index(i).Interface()
+		}
+		return encodeArray(items, out)
+	}
+	if rv.Kind() != reflect.Struct {
+		panic(ErrorUnknownType)
+	}
+
+	fields := fieldsOf(rv.Type())
+	pairs := make([][2]interface{}, 0, len(fields))
+	for _, f := range fields {
+		fv := rv.FieldByIndex(f.index)
+		if f.omitempty && fv.IsZero() {
+			continue
+		}
+		pairs = append(pairs, [2]interface{}{f.key, fv.Interface()})
+	}
+	return encodeMap(pairs, out)
+}
+
+// Unmarshal decodes a CBOR map from `buf` into the struct pointed to
+// by `v`, matching fields by their `cbor` tag the same way Marshal
+// does. `v` must be a non-nil pointer to a struct.
+func Unmarshal(buf []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return ErrorUnknownType
+	}
+	item, _ := decode(buf)
+	pairs, ok := item.([][2]interface{})
+	if !ok {
+		return ErrorUnknownType
+	}
+
+	setStructFields(rv.Elem(), pairs)
+	return nil
+}
+
+// setStructFields assigns each decoded key/value pair in `pairs` onto
+// the matching tagged field of struct value `rv`.
+func setStructFields(rv reflect.Value, pairs [][2]interface{}) {
+	fields := fieldsOf(rv.Type())
+	for _, kv := range pairs {
+		for _, f := range fields {
+			if !keysMatch(f.key, kv[0]) {
+				continue
+			}
+			setField(rv.FieldByIndex(f.index), kv[1])
+			break
+		}
+	}
+}
+
+// Marshal encodes `v`, which must be a struct or pointer to struct,
+// into `out` as a CBOR map, honouring `cbor` struct tags. It is a thin
+// wrapper over encode() provided for symmetry with Unmarshal.
+func Marshal(v interface{}, out []byte) int {
+	return encode(v, out)
+}
+
+func keysMatch(fieldKey, docKey interface{}) bool {
+	switch fk := fieldKey.(type) {
+	case string:
+		s, ok := docKey.(string)
+		return ok && s == fk
+	case int64:
+		switch dk := docKey.(type) {
+		case int64:
+			return dk == fk
+		case uint64:
+			return int64(dk) == fk
+		}
+	}
+	return false
+}
+
+// setField assigns a decoded CBOR value to a struct field, converting
+// numeric width/signedness as needed.
+func setField(fv reflect.Value, value interface{}) {
+	if !fv.CanSet() {
+		return
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		if s, ok := value.(string); ok {
+			fv.SetString(s)
+		}
+	case reflect.Bool:
+		if b, ok := value.(bool); ok {
+			fv.SetBool(b)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch n := value.(type) {
+		case int64:
+			fv.SetInt(n)
+		case uint64:
+			fv.SetInt(int64(n))
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		switch n := value.(type) {
+		case uint64:
+			fv.SetUint(n)
+		case int64:
+			fv.SetUint(uint64(n))
+		}
+	case reflect.Float32, reflect.Float64:
+		switch n := value.(type) {
+		case float32:
+			fv.SetFloat(float64(n))
+		case float64:
+			fv.SetFloat(n)
+		}
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() == reflect.Uint8 {
+			if b, ok := value.([]byte); ok {
+				fv.SetBytes(b)
+			}
+			return
+		}
+		items, ok := value.([]interface{})
+		if !ok {
+			return
+		}
+		slice := reflect.MakeSlice(fv.Type(), len(items), len(items))
+		for i, item := range items {
+			setField(slice.Index(i), item)
+		}
+		fv.Set(slice)
+	case reflect.Struct:
+		if pairs, ok := value.([][2]interface{}); ok {
+			setStructFields(fv, pairs)
+		}
+	case reflect.Ptr:
+		if value == nil {
+			return
+		}
+		if fv.IsNil() {
+			fv.Set(reflect.New(fv.Type().Elem()))
+		}
+		setField(fv.Elem(), value)
+	}
+}
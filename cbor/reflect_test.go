@@ -0,0 +1,99 @@
+package cbor
+
+import "testing"
+
+type reflectTestInner struct {
+	B bool
+}
+
+type reflectTestStruct struct {
+	Name    string            `cbor:"name"`
+	Age     int               `cbor:"age"`
+	Tag     int64             `cbor:"3,keyasint"`
+	Skip    string            `cbor:"-"`
+	Empty   string            `cbor:"empty,omitempty"`
+	Nested  reflectTestInner  `cbor:"nested"`
+	Tags    []string          `cbor:"tags"`
+	Ignored string            // untagged, falls back to field name
+	NestedP *reflectTestInner `cbor:"nestedp"`
+}
+
+func TestMarshalUnmarshalStructRoundTrip(t *testing.T) {
+	in := reflectTestStruct{
+		Name:    "alice",
+		Age:     30,
+		Tag:     7,
+		Skip:    "must not appear",
+		Nested:  reflectTestInner{B: true},
+		Tags:    []string{"a", "b"},
+		Ignored: "kept",
+		NestedP: &reflectTestInner{B: false},
+	}
+	out := make([]byte, 512)
+	n := Marshal(in, out)
+
+	var got reflectTestStruct
+	if err := Unmarshal(out[:n], &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	if got.Name != in.Name || got.Age != in.Age || got.Tag != in.Tag {
+		t.Fatalf("got = %+v, want %+v", got, in)
+	}
+	if got.Skip != "" {
+		t.Fatalf("Skip field leaked through a '-' tag: %q", got.Skip)
+	}
+	if got.Nested.B != true {
+		t.Fatalf("Nested.B = %v, want true", got.Nested.B)
+	}
+	if len(got.Tags) != 2 || got.Tags[0] != "a" || got.Tags[1] != "b" {
+		t.Fatalf("Tags = %v", got.Tags)
+	}
+	if got.Ignored != in.Ignored {
+		t.Fatalf("Ignored = %q, want %q under field-name key", got.Ignored, in.Ignored)
+	}
+	if got.NestedP == nil || got.NestedP.B != false {
+		t.Fatalf("NestedP = %v", got.NestedP)
+	}
+}
+
+func TestMarshalOmitsEmptyField(t *testing.T) {
+	in := reflectTestStruct{Name: "bob"}
+	out := make([]byte, 512)
+	n := Marshal(in, out)
+
+	item, _ := decode(out[:n])
+	pairs, ok := item.([][2]interface{})
+	if !ok {
+		t.Fatalf("decode = %v, want map", item)
+	}
+	for _, kv := range pairs {
+		if kv[0] == "empty" {
+			t.Fatalf("omitempty field 'empty' was encoded: %v", kv)
+		}
+	}
+}
+
+func TestUnmarshalRejectsNonStructTarget(t *testing.T) {
+	var s string
+	if err := Unmarshal([]byte{0x00}, &s); err != ErrorUnknownType {
+		t.Fatalf("Unmarshal(non-struct target) = %v, want ErrorUnknownType", err)
+	}
+}
+
+func TestSetFieldHandlesNestedSliceOfStructs(t *testing.T) {
+	type withSlice struct {
+		Items []reflectTestInner `cbor:"items"`
+	}
+	in := withSlice{Items: []reflectTestInner{{B: true}, {B: false}}}
+	out := make([]byte, 512)
+	n := Marshal(in, out)
+
+	var got withSlice
+	if err := Unmarshal(out[:n], &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if len(got.Items) != 2 || got.Items[0].B != true || got.Items[1].B != false {
+		t.Fatalf("Items = %+v", got.Items)
+	}
+}
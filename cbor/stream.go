@@ -0,0 +1,364 @@
+package cbor
+
+import "io"
+
+// Encoder writes a sequence of CBOR data-items to an io.Writer, in the
+// style of a CBOR sequence (RFC 8742): concatenated top-level items
+// with no wrapping array required.
+type Encoder struct {
+	w   io.Writer
+	buf []byte
+}
+
+// NewEncoder returns an Encoder that writes to `w`.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w, buf: make([]byte, 512)}
+}
+
+// Encode writes `v` as a single CBOR data-item.
+func (enc *Encoder) Encode(v interface{}) error {
+	enc.buf = Append(enc.buf[:0], v)
+	_, err := enc.w.Write(enc.buf)
+	return err
+}
+
+// EncodeArrayHeader writes a definite-length array header for `n`
+// items; the caller is responsible for calling Encode n times
+// immediately afterwards.
+func (enc *Encoder) EncodeArrayHeader(n int) error {
+	return enc.writeSized(9, func(buf []byte) int {
+		x := encodeUint64(uint64(n), buf)
+		buf[0] = (buf[0] & 0x1f) | type4
+		return x
+	})
+}
+
+// EncodeMapHeader writes a definite-length map header for `n` pairs;
+// the caller is responsible for calling Encode 2*n times immediately
+// afterwards (key, value, key, value, ...).
+func (enc *Encoder) EncodeMapHeader(n int) error {
+	return enc.writeSized(9, func(buf []byte) int {
+		x := encodeUint64(uint64(n), buf)
+		buf[0] = (buf[0] & 0x1f) | type5
+		return x
+	})
+}
+
+// StartIndefiniteArray writes the header of an indefinite-length
+// array. Terminate it with EndIndefinite.
+func (enc *Encoder) StartIndefiniteArray() error {
+	return enc.writeSized(1, encodeArrayStart)
+}
+
+// StartIndefiniteMap writes the header of an indefinite-length map.
+// Terminate it with EndIndefinite.
+func (enc *Encoder) StartIndefiniteMap() error {
+	return enc.writeSized(1, encodeMapStart)
+}
+
+// StartIndefiniteBytes writes the header of an indefinite-length byte
+// string, whose chunks are each subsequently written with Encode of a
+// []byte. Terminate it with EndIndefinite.
+func (enc *Encoder) StartIndefiniteBytes() error {
+	return enc.writeSized(1, encodeBytesStart)
+}
+
+// StartIndefiniteText writes the header of an indefinite-length text
+// string, whose chunks are each subsequently written with Encode of a
+// string. Terminate it with EndIndefinite.
+func (enc *Encoder) StartIndefiniteText() error {
+	return enc.writeSized(1, encodeTextStart)
+}
+
+// EndIndefinite writes the break-stop code that closes an
+// indefinite-length array, map, byte-string or text-string started
+// above.
+func (enc *Encoder) EndIndefinite() error {
+	return enc.writeSized(1, encodeBreakStop)
+}
+
+func (enc *Encoder) writeSized(maxLen int, fn func([]byte) int) error {
+	if cap(enc.buf) < maxLen {
+		enc.buf = make([]byte, maxLen)
+	}
+	n := fn(enc.buf[:maxLen])
+	_, err := enc.w.Write(enc.buf[:n])
+	return err
+}
+
+// Decoder reads a sequence of CBOR data-items from an io.Reader,
+// refilling its internal buffer across chunk boundaries so that even a
+// single indefinite-length array/map/string spanning many reads can be
+// consumed without the caller pre-buffering the whole stream.
+type Decoder struct {
+	r      io.Reader
+	buf    []byte
+	off    int
+	filled int
+}
+
+// NewDecoder returns a Decoder that reads from `r`.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r, buf: make([]byte, 4096)}
+}
+
+// Decode reads the next CBOR data-item and stores it into `v`, which
+// must be a non-nil pointer to interface{} (or to a concrete Go type
+// matching the item's shape, e.g. *string, *int64, *[]byte).
+func (dec *Decoder) Decode(v interface{}) error {
+	if !dec.fill(1) {
+		return io.EOF
+	}
+	item, n, err := dec.readItem()
+	if err != nil {
+		return err
+	}
+	dec.off += n
+	return assignDecoded(v, item)
+}
+
+// readItem decodes one complete top-level item out of the buffer,
+// refilling as needed until the item's length is known and available.
+func (dec *Decoder) readItem() (interface{}, int, error) {
+	for {
+		item, n, ok, err := tryDecode(dec.buf[dec.off:dec.filled])
+		if err != nil {
+			return nil, 0, err
+		}
+		if ok {
+			return item, n, nil
+		}
+		if !dec.fill(dec.filled - dec.off + 1) {
+			return nil, 0, io.ErrUnexpectedEOF
+		}
+	}
+}
+
+// fill ensures at least `n` bytes are buffered from the current
+// offset, compacting and growing the backing array as needed.
+func (dec *Decoder) fill(n int) bool {
+	for dec.filled-dec.off < n {
+		if dec.off > 0 {
+			copy(dec.buf, dec.buf[dec.off:dec.filled])
+			dec.filled -= dec.off
+			dec.off = 0
+		}
+		if dec.filled == len(dec.buf) {
+			grown := make([]byte, len(dec.buf)*2)
+			copy(grown, dec.buf[:dec.filled])
+			dec.buf = grown
+		}
+		m, err := dec.r.Read(dec.buf[dec.filled:])
+		dec.filled += m
+		if m == 0 && err != nil {
+			return dec.filled-dec.off >= n
+		}
+	}
+	return true
+}
+
+// tryDecode attempts to decode one item from `buf`. ok is false only
+// when buf doesn't yet hold a complete item (ErrTruncated) — the
+// caller should buffer more and retry. Any other failure is a genuine
+// decode error, returned as err instead of being mistaken for "need
+// more data" and spun on until EOF.
+//
+// Unlike Wellformed, tryDecode probes only for truncation: it does not
+// enforce StrictDecoder's configurable validity limits (string-only
+// map keys, nesting depth, element/byte-string caps), so the general
+// streaming Decoder can read any well-formed CBOR those limits would
+// reject — including the integer-keyed maps this library's own
+// cose/cwt subpackage emits. A caller that wants those limits enforced
+// should validate with Wellformed/StrictDecoder itself.
+func tryDecode(buf []byte) (item interface{}, n int, ok bool, err error) {
+	if len(buf) == 0 {
+		return nil, 0, false, nil
+	}
+	n, err = itemLength(buf)
+	if err != nil {
+		if err == ErrTruncated {
+			return nil, 0, false, nil
+		}
+		return nil, 0, false, err
+	}
+	item, _ = decode(buf[:n])
+	return item, n, true, nil
+}
+
+// itemLength measures the single CBOR data-item at the start of buf
+// without materializing it, the truncation-only counterpart of
+// Wellformed: it accepts any map key type and any nesting depth,
+// rejecting only a buffer that ends before the item does (ErrTruncated)
+// or a header the grammar itself forbids, such as an indefinite-length
+// tag (ErrReservedInfo).
+func itemLength(buf []byte) (int, error) {
+	if len(buf) < 1 {
+		return 0, ErrTruncated
+	}
+	mt, ai := major(buf[0]), info(buf[0])
+	switch mt {
+	case type0, type1:
+		return wellformedHead(buf, ai)
+
+	case type2, type3:
+		if ai == indefiniteLength {
+			return indefiniteStringLength(buf, mt)
+		}
+		length, n, err := wellformedLength(buf, ai)
+		if err != nil {
+			return 0, err
+		}
+		ln, err := boundedLen(length, len(buf), ErrTruncated)
+		if err != nil {
+			return 0, err
+		}
+		if len(buf)-n < ln {
+			return 0, ErrTruncated
+		}
+		return n + ln, nil
+
+	case type4:
+		return containerLength(buf, ai, true)
+
+	case type5:
+		return containerLength(buf, ai, false)
+
+	case type6:
+		if ai == indefiniteLength {
+			return 0, ErrReservedInfo
+		}
+		_, n, err := wellformedLength(buf, ai)
+		if err != nil {
+			return 0, err
+		}
+		m, err := itemLength(buf[n:])
+		if err != nil {
+			return 0, err
+		}
+		return n + m, nil
+
+	default: // type7
+		return wellformedSimple(buf, ai)
+	}
+}
+
+func indefiniteStringLength(buf []byte, mt byte) (int, error) {
+	n := 1
+	for {
+		if n >= len(buf) {
+			return 0, ErrTruncated
+		}
+		if buf[n] == hdr(type7, itemBreak) {
+			return n + 1, nil
+		}
+		if major(buf[n]) != mt || info(buf[n]) == indefiniteLength {
+			return 0, ErrReservedInfo
+		}
+		length, m, err := wellformedLength(buf[n:], info(buf[n]))
+		if err != nil {
+			return 0, err
+		}
+		ln, err := boundedLen(length, len(buf), ErrTruncated)
+		if err != nil {
+			return 0, err
+		}
+		if len(buf)-n-m < ln {
+			return 0, ErrTruncated
+		}
+		n += m + ln
+	}
+}
+
+func containerLength(buf []byte, ai byte, isArray bool) (int, error) {
+	if ai == indefiniteLength {
+		n := 1
+		for {
+			if n >= len(buf) {
+				return 0, ErrTruncated
+			}
+			if buf[n] == hdr(type7, itemBreak) {
+				return n + 1, nil
+			}
+			m, err := containerElementLength(buf[n:], isArray)
+			if err != nil {
+				return 0, err
+			}
+			n += m
+		}
+	}
+
+	count, n, err := wellformedLength(buf, ai)
+	if err != nil {
+		return 0, err
+	}
+	for i := uint64(0); i < count; i++ {
+		m, err := containerElementLength(buf[n:], isArray)
+		if err != nil {
+			return 0, err
+		}
+		n += m
+	}
+	return n, nil
+}
+
+// containerElementLength measures one array item, or one map key+value
+// pair, imposing none of wellformedElement's map-key-type or
+// duplicate-key restrictions.
+func containerElementLength(buf []byte, isArray bool) (int, error) {
+	n, err := itemLength(buf)
+	if err != nil {
+		return 0, err
+	}
+	if isArray {
+		return n, nil
+	}
+	m, err := itemLength(buf[n:])
+	if err != nil {
+		return 0, err
+	}
+	return n + m, nil
+}
+
+func assignDecoded(v interface{}, item interface{}) error {
+	switch p := v.(type) {
+	case *interface{}:
+		*p = item
+		return nil
+	case *string:
+		s, ok := item.(string)
+		if !ok {
+			return ErrorUnknownType
+		}
+		*p = s
+		return nil
+	case *[]byte:
+		b, ok := item.([]byte)
+		if !ok {
+			return ErrorUnknownType
+		}
+		*p = b
+		return nil
+	case *int64:
+		i, ok := item.(int64)
+		if !ok {
+			return ErrorUnknownType
+		}
+		*p = i
+		return nil
+	case *uint64:
+		u, ok := item.(uint64)
+		if !ok {
+			return ErrorUnknownType
+		}
+		*p = u
+		return nil
+	case *float64:
+		f, ok := item.(float64)
+		if !ok {
+			return ErrorUnknownType
+		}
+		*p = f
+		return nil
+	}
+	return ErrorUnknownType
+}
@@ -0,0 +1,121 @@
+package cbor
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestEncoderDecoderRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	values := []interface{}{int64(1), "two", []byte{3, 3, 3}, true}
+	for _, v := range values {
+		if err := enc.Encode(v); err != nil {
+			t.Fatalf("Encode(%v): %v", v, err)
+		}
+	}
+
+	dec := NewDecoder(&buf)
+	for _, want := range values {
+		var got interface{}
+		if err := dec.Decode(&got); err != nil {
+			t.Fatalf("Decode: %v", err)
+		}
+		if s, ok := want.([]byte); ok {
+			g, ok := got.([]byte)
+			if !ok || !bytes.Equal(g, s) {
+				t.Fatalf("Decode = %v, want %v", got, want)
+			}
+			continue
+		}
+		if got != want {
+			t.Fatalf("Decode = %v, want %v", got, want)
+		}
+	}
+	if err := dec.Decode(new(interface{})); err != io.EOF {
+		t.Fatalf("Decode at end of stream = %v, want io.EOF", err)
+	}
+}
+
+// oneByteReader forces the Decoder to refill its buffer repeatedly,
+// exercising fill()'s compaction/growth path across many short reads.
+type oneByteReader struct{ data []byte }
+
+func (r *oneByteReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, r.data[:1])
+	r.data = r.data[1:]
+	return n, nil
+}
+
+func TestDecoderAcrossShortReads(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	if err := enc.Encode("a reasonably long string to span several single-byte reads"); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	dec := NewDecoder(&oneByteReader{data: buf.Bytes()})
+	var got string
+	if err := dec.Decode(&got); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if got != "a reasonably long string to span several single-byte reads" {
+		t.Fatalf("Decode = %q", got)
+	}
+}
+
+func TestDecoderAcceptsIntegerKeyedMap(t *testing.T) {
+	// Regression: tryDecode must not impose StrictDecoder's
+	// string-only-map-key policy on the general streaming Decoder.
+	var dst []byte
+	dst = AppendMapStart(dst)
+	dst = AppendInt(dst, 1)
+	dst = AppendText(dst, "a")
+	dst = AppendBreak(dst)
+
+	dec := NewDecoder(bytes.NewReader(dst))
+	var got interface{}
+	if err := dec.Decode(&got); err != nil {
+		t.Fatalf("Decode(integer-keyed map): %v", err)
+	}
+	pairs, ok := got.([][2]interface{})
+	if !ok || len(pairs) != 1 || pairs[0][0] != uint64(1) || pairs[0][1] != "a" {
+		t.Fatalf("Decode = %v", got)
+	}
+}
+
+func TestDecoderAcceptsDeepNesting(t *testing.T) {
+	// Regression: tryDecode must not impose StrictDecoder's 32-level
+	// nesting cap on the general streaming Decoder.
+	const depth = 40
+	var dst []byte
+	for i := 0; i < depth; i++ {
+		dst = AppendArrayStart(dst)
+	}
+	dst = AppendInt(dst, 1)
+	for i := 0; i < depth; i++ {
+		dst = AppendBreak(dst)
+	}
+
+	dec := NewDecoder(bytes.NewReader(dst))
+	var got interface{}
+	if err := dec.Decode(&got); err != nil {
+		t.Fatalf("Decode(depth %d): %v", depth, err)
+	}
+}
+
+func TestDecoderRejectsTruncatedInput(t *testing.T) {
+	var dst []byte
+	dst = AppendText(dst, "hello")
+	truncated := dst[:len(dst)-2]
+
+	dec := NewDecoder(bytes.NewReader(truncated))
+	var got interface{}
+	if err := dec.Decode(&got); err != io.ErrUnexpectedEOF {
+		t.Fatalf("Decode(truncated) = %v, want io.ErrUnexpectedEOF", err)
+	}
+}
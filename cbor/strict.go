@@ -0,0 +1,409 @@
+package cbor
+
+import "encoding/binary"
+import "errors"
+
+// decode()/cborDecoders index straight into the input buffer and
+// panic on anything truncated or reserved, which is fine for buffers
+// this process produced itself but unsafe for parsing untrusted
+// network payloads (CoAP, COSE). StrictDecoder and Wellformed walk
+// the same grammar with bounds checks and configurable limits
+// instead, returning one of the errors below rather than panicking.
+var (
+	// ErrTruncated means the buffer ends before a header, argument or
+	// content the grammar requires.
+	ErrTruncated = errors.New("cbor.truncated")
+
+	// ErrReservedInfo means a header used additional-information 28-30,
+	// or an indefinite-length marker somewhere the grammar forbids it
+	// (type0/type1/type6, or an indefinite chunk whose major type
+	// doesn't match the string it's chunking).
+	ErrReservedInfo = errors.New("cbor.reservedInfo")
+
+	// ErrDuplicateMapKey means a map had the same text-string key twice.
+	ErrDuplicateMapKey = errors.New("cbor.duplicateMapKey")
+
+	// ErrNonStringMapKey means a map key was not a text string.
+	ErrNonStringMapKey = errors.New("cbor.nonStringMapKey")
+
+	// ErrUnknownSimple means a type7 simple-value byte outside the
+	// ranges this package assigns a meaning to (false/true/null/
+	// undefined/float16/float32/float64, or an encoded simple type
+	// byte below 32, which the spec reserves for the short form).
+	ErrUnknownSimple = errors.New("cbor.unknownSimple")
+
+	// ErrNestingTooDeep means an array, map or tagged item nested
+	// past DecOptions.MaxNestingDepth.
+	ErrNestingTooDeep = errors.New("cbor.nestingTooDeep")
+
+	// ErrTooManyElements means an array or map's element count exceeds
+	// DecOptions.MaxArrayElements/MaxMapPairs.
+	ErrTooManyElements = errors.New("cbor.tooManyElements")
+
+	// ErrByteStringTooLong means a byte or text string (or the sum of
+	// an indefinite-length string's chunks) exceeds
+	// DecOptions.MaxByteStringLen.
+	ErrByteStringTooLong = errors.New("cbor.byteStringTooLong")
+
+	// ErrTotalSizeExceeded means the data-item as a whole exceeds
+	// DecOptions.MaxTotalBytes.
+	ErrTotalSizeExceeded = errors.New("cbor.totalSizeExceeded")
+)
+
+// DecOptions bounds the resources StrictDecoder.Decode and Wellformed
+// will spend validating a single data-item, so a caller can firewall
+// off a peer that sends deeply-nested or oversized input before it
+// ever reaches application code.
+type DecOptions struct {
+	MaxNestingDepth  int
+	MaxArrayElements int
+	MaxMapPairs      int
+	MaxByteStringLen int
+	MaxTotalBytes    int
+}
+
+// DefaultDecOptions returns conservative limits suitable for decoding
+// payloads handed in by an untrusted peer.
+func DefaultDecOptions() DecOptions {
+	return DecOptions{
+		MaxNestingDepth:  32,
+		MaxArrayElements: 1 << 20,
+		MaxMapPairs:      1 << 20,
+		MaxByteStringLen: 1 << 24,
+		MaxTotalBytes:    1 << 24,
+	}
+}
+
+// StrictDecoder decodes a single CBOR data-item from a byte slice
+// under a fixed DecOptions, validating well-formedness first so that
+// decode() never sees input it would panic on.
+type StrictDecoder struct {
+	opts DecOptions
+}
+
+// NewStrictDecoder returns a StrictDecoder enforcing `opts`.
+func NewStrictDecoder(opts DecOptions) *StrictDecoder {
+	return &StrictDecoder{opts: opts}
+}
+
+// Decode validates the data-item at the start of `buf` against the
+// decoder's DecOptions and, if it is well-formed and within limits,
+// materializes it the same way the package-level decode does. It
+// returns the value, the number of bytes consumed, and any error.
+func (d *StrictDecoder) Decode(buf []byte) (interface{}, int, error) {
+	n, err := wellformedItem(buf, 0, d.opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	if n > d.opts.MaxTotalBytes {
+		return nil, 0, ErrTotalSizeExceeded
+	}
+	item, _ := decode(buf[:n])
+	return item, n, nil
+}
+
+// Wellformed reports whether `buf` begins with a single well-formed
+// CBOR data-item per RFC 8949 §1.2, under DefaultDecOptions limits,
+// without materializing it into a Go value — a cheap fast-path for
+// firewalling data before it reaches a value decoder.
+func Wellformed(buf []byte) error {
+	opts := DefaultDecOptions()
+	n, err := wellformedItem(buf, 0, opts)
+	if err != nil {
+		return err
+	}
+	if n > opts.MaxTotalBytes {
+		return ErrTotalSizeExceeded
+	}
+	return nil
+}
+
+// wellformedItem validates and measures the single data-item at the
+// start of buf, recursing into containers and tagged items with
+// `depth` tracking nesting against opts.MaxNestingDepth.
+func wellformedItem(buf []byte, depth int, opts DecOptions) (int, error) {
+	if depth > opts.MaxNestingDepth {
+		return 0, ErrNestingTooDeep
+	}
+	if len(buf) < 1 {
+		return 0, ErrTruncated
+	}
+
+	mt, ai := major(buf[0]), info(buf[0])
+	switch mt {
+	case type0, type1:
+		return wellformedHead(buf, ai)
+
+	case type2, type3:
+		if ai == indefiniteLength {
+			return wellformedIndefiniteString(buf, mt, opts)
+		}
+		length, n, err := wellformedLength(buf, ai)
+		if err != nil {
+			return 0, err
+		}
+		ln, err := boundedLen(length, opts.MaxByteStringLen, ErrByteStringTooLong)
+		if err != nil {
+			return 0, err
+		}
+		if len(buf)-n < ln {
+			return 0, ErrTruncated
+		}
+		return n + ln, nil
+
+	case type4:
+		return wellformedContainer(buf, ai, depth, opts, true)
+
+	case type5:
+		return wellformedContainer(buf, ai, depth, opts, false)
+
+	case type6:
+		if ai == indefiniteLength {
+			return 0, ErrReservedInfo
+		}
+		_, n, err := wellformedLength(buf, ai)
+		if err != nil {
+			return 0, err
+		}
+		m, err := wellformedItem(buf[n:], depth+1, opts)
+		if err != nil {
+			return 0, err
+		}
+		return n + m, nil
+
+	default: // type7
+		return wellformedSimple(buf, ai)
+	}
+}
+
+// wellformedHead validates an integer (type0/type1) header+argument,
+// whose additional-information byte is `ai`.
+func wellformedHead(buf []byte, ai byte) (int, error) {
+	switch {
+	case ai < info24:
+		return 1, nil
+	case ai == info24:
+		return wellformedNeed(buf, 2)
+	case ai == info25:
+		return wellformedNeed(buf, 3)
+	case ai == info26:
+		return wellformedNeed(buf, 5)
+	case ai == info27:
+		return wellformedNeed(buf, 9)
+	}
+	return 0, ErrReservedInfo // 28..31: reserved, indefinite not allowed here
+}
+
+func wellformedNeed(buf []byte, n int) (int, error) {
+	if len(buf) < n {
+		return 0, ErrTruncated
+	}
+	return n, nil
+}
+
+// wellformedLength reads the length/tag-number argument that follows
+// a header byte with additional-information `ai`, returning the
+// decoded value and the number of header+argument bytes it occupies.
+func wellformedLength(buf []byte, ai byte) (uint64, int, error) {
+	switch {
+	case ai < info24:
+		return uint64(ai), 1, nil
+	case ai == info24:
+		if len(buf) < 2 {
+			return 0, 0, ErrTruncated
+		}
+		return uint64(buf[1]), 2, nil
+	case ai == info25:
+		if len(buf) < 3 {
+			return 0, 0, ErrTruncated
+		}
+		return uint64(binary.BigEndian.Uint16(buf[1:])), 3, nil
+	case ai == info26:
+		if len(buf) < 5 {
+			return 0, 0, ErrTruncated
+		}
+		return uint64(binary.BigEndian.Uint32(buf[1:])), 5, nil
+	case ai == info27:
+		if len(buf) < 9 {
+			return 0, 0, ErrTruncated
+		}
+		return binary.BigEndian.Uint64(buf[1:]), 9, nil
+	}
+	return 0, 0, ErrReservedInfo
+}
+
+// boundedLen converts a wire-format uint64 length/count into an int,
+// reporting `tooLong` instead of silently wrapping when it exceeds
+// `max` (or int's own range) — a header can claim a length up to
+// 2^64-1, and narrowing that to int before checking it against max
+// would let an oversized value wrap negative and slip past the check.
+func boundedLen(v uint64, max int, tooLong error) (int, error) {
+	if max < 0 || v > uint64(max) {
+		return 0, tooLong
+	}
+	return int(v), nil
+}
+
+// wellformedIndefiniteString validates an indefinite-length byte or
+// text string: zero or more definite-length chunks of the same major
+// type `mt`, terminated by a break code.
+func wellformedIndefiniteString(buf []byte, mt byte, opts DecOptions) (int, error) {
+	n, total := 1, 0
+	for {
+		if n >= len(buf) {
+			return 0, ErrTruncated
+		}
+		if buf[n] == hdr(type7, itemBreak) {
+			return n + 1, nil
+		}
+		if major(buf[n]) != mt || info(buf[n]) == indefiniteLength {
+			return 0, ErrReservedInfo
+		}
+		length, m, err := wellformedLength(buf[n:], info(buf[n]))
+		if err != nil {
+			return 0, err
+		}
+		ln, err := boundedLen(length, opts.MaxByteStringLen-total, ErrByteStringTooLong)
+		if err != nil {
+			return 0, err
+		}
+		total += ln
+		if len(buf)-n-m < ln {
+			return 0, ErrTruncated
+		}
+		n += m + ln
+	}
+}
+
+// wellformedContainer validates a definite- or indefinite-length
+// array (isArray) or map, enforcing element-count limits and, for
+// maps, that every key is a non-duplicate text string.
+func wellformedContainer(buf []byte, ai byte, depth int, opts DecOptions, isArray bool) (int, error) {
+	limit := opts.MaxArrayElements
+	if !isArray {
+		limit = opts.MaxMapPairs
+	}
+	var seen map[string]bool
+	if !isArray {
+		seen = make(map[string]bool)
+	}
+
+	if ai == indefiniteLength {
+		n, count := 1, 0
+		for {
+			if n >= len(buf) {
+				return 0, ErrTruncated
+			}
+			if buf[n] == hdr(type7, itemBreak) {
+				return n + 1, nil
+			}
+			if count >= limit {
+				return 0, ErrTooManyElements
+			}
+			count++
+			m, err := wellformedElement(buf[n:], depth, opts, isArray, seen)
+			if err != nil {
+				return 0, err
+			}
+			n += m
+		}
+	}
+
+	count, n, err := wellformedLength(buf, ai)
+	if err != nil {
+		return 0, err
+	}
+	if limit < 0 || count > uint64(limit) {
+		return 0, ErrTooManyElements
+	}
+	for i := uint64(0); i < count; i++ {
+		m, err := wellformedElement(buf[n:], depth, opts, isArray, seen)
+		if err != nil {
+			return 0, err
+		}
+		n += m
+	}
+	return n, nil
+}
+
+// wellformedElement validates one array item, or one map key+value
+// pair (tracking `seen` keys for duplicates), returning the bytes it
+// occupies.
+func wellformedElement(buf []byte, depth int, opts DecOptions, isArray bool, seen map[string]bool) (int, error) {
+	if isArray {
+		return wellformedItem(buf, depth+1, opts)
+	}
+	key, n, err := wellformedMapKey(buf, opts, seen)
+	if err != nil {
+		return 0, err
+	}
+	_ = key
+	m, err := wellformedItem(buf[n:], depth+1, opts)
+	if err != nil {
+		return 0, err
+	}
+	return n + m, nil
+}
+
+// wellformedMapKey validates that the item at the start of buf is a
+// definite-length text string, recording it in `seen` and rejecting a
+// repeat. Keys chunked as an indefinite-length text string are valid
+// CBOR but, since they are uncommon and awkward to compare cheaply,
+// are not tracked for duplicates.
+func wellformedMapKey(buf []byte, opts DecOptions, seen map[string]bool) (string, int, error) {
+	if len(buf) < 1 {
+		return "", 0, ErrTruncated
+	}
+	if major(buf[0]) != type3 {
+		return "", 0, ErrNonStringMapKey
+	}
+	ai := info(buf[0])
+	if ai == indefiniteLength {
+		n, err := wellformedIndefiniteString(buf, type3, opts)
+		return "", n, err
+	}
+	length, n, err := wellformedLength(buf, ai)
+	if err != nil {
+		return "", 0, err
+	}
+	ln, err := boundedLen(length, opts.MaxByteStringLen, ErrByteStringTooLong)
+	if err != nil {
+		return "", 0, err
+	}
+	if len(buf)-n < ln {
+		return "", 0, ErrTruncated
+	}
+	key := string(buf[n : n+ln])
+	if seen[key] {
+		return "", 0, ErrDuplicateMapKey
+	}
+	seen[key] = true
+	return key, n + ln, nil
+}
+
+// wellformedSimple validates a type7 item: a short simple value, the
+// false/true/null/undefined constants, a byte-extended simple type,
+// a float16/32/64, or a break code (only valid when an enclosing
+// indefinite-length container consumes it directly).
+func wellformedSimple(buf []byte, ai byte) (int, error) {
+	switch {
+	case ai < simpleTypeByte:
+		return 1, nil
+	case ai == simpleTypeByte:
+		n, err := wellformedNeed(buf, 2)
+		if err != nil {
+			return 0, err
+		}
+		if buf[1] < 32 {
+			return 0, ErrUnknownSimple
+		}
+		return n, nil
+	case ai == flt16:
+		return wellformedNeed(buf, 3)
+	case ai == flt32:
+		return wellformedNeed(buf, 5)
+	case ai == flt64:
+		return wellformedNeed(buf, 9)
+	}
+	return 0, ErrUnknownSimple // 28..30 reserved, or a bare break
+}
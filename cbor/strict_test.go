@@ -0,0 +1,104 @@
+package cbor
+
+import "testing"
+
+func TestStrictDecoderAcceptsWellformedInput(t *testing.T) {
+	var dst []byte
+	dst = AppendMapStart(dst)
+	dst = AppendText(dst, "a")
+	dst = AppendInt(dst, 1)
+	dst = AppendBreak(dst)
+
+	dec := NewStrictDecoder(DefaultDecOptions())
+	item, n, err := dec.Decode(dst)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if n != len(dst) {
+		t.Fatalf("Decode consumed %d bytes, want %d", n, len(dst))
+	}
+	pairs, ok := item.([][2]interface{})
+	if !ok || len(pairs) != 1 || pairs[0][0] != "a" {
+		t.Fatalf("Decode = %v", item)
+	}
+}
+
+func TestWellformedRejectsTruncatedInput(t *testing.T) {
+	var dst []byte
+	dst = AppendText(dst, "hello")
+	truncated := dst[:len(dst)-2]
+
+	if err := Wellformed(truncated); err != ErrTruncated {
+		t.Fatalf("Wellformed(truncated) = %v, want ErrTruncated", err)
+	}
+}
+
+func TestWellformedRejectsNonStringMapKey(t *testing.T) {
+	var dst []byte
+	dst = AppendMapStart(dst)
+	dst = AppendInt(dst, 1)
+	dst = AppendText(dst, "a")
+	dst = AppendBreak(dst)
+
+	if err := Wellformed(dst); err != ErrNonStringMapKey {
+		t.Fatalf("Wellformed(int-keyed map) = %v, want ErrNonStringMapKey", err)
+	}
+}
+
+func TestWellformedRejectsDuplicateMapKey(t *testing.T) {
+	var dst []byte
+	dst = AppendMapStart(dst)
+	dst = AppendText(dst, "a")
+	dst = AppendInt(dst, 1)
+	dst = AppendText(dst, "a")
+	dst = AppendInt(dst, 2)
+	dst = AppendBreak(dst)
+
+	if err := Wellformed(dst); err != ErrDuplicateMapKey {
+		t.Fatalf("Wellformed(duplicate key) = %v, want ErrDuplicateMapKey", err)
+	}
+}
+
+func TestWellformedRejectsNestingTooDeep(t *testing.T) {
+	opts := DefaultDecOptions()
+	opts.MaxNestingDepth = 2
+	var dst []byte
+	for i := 0; i < 4; i++ {
+		dst = AppendArrayStart(dst)
+	}
+	dst = AppendInt(dst, 1)
+	for i := 0; i < 4; i++ {
+		dst = AppendBreak(dst)
+	}
+
+	dec := NewStrictDecoder(opts)
+	if _, _, err := dec.Decode(dst); err != ErrNestingTooDeep {
+		t.Fatalf("Decode(too deep) = %v, want ErrNestingTooDeep", err)
+	}
+}
+
+func TestWellformedRejectsTooManyElements(t *testing.T) {
+	opts := DefaultDecOptions()
+	opts.MaxArrayElements = 1
+	var dst []byte
+	dst = AppendArrayStart(dst)
+	dst = AppendInt(dst, 1)
+	dst = AppendInt(dst, 2)
+	dst = AppendBreak(dst)
+
+	dec := NewStrictDecoder(opts)
+	if _, _, err := dec.Decode(dst); err != ErrTooManyElements {
+		t.Fatalf("Decode(too many elements) = %v, want ErrTooManyElements", err)
+	}
+}
+
+func TestWellformedRejectsOversizedByteString(t *testing.T) {
+	opts := DefaultDecOptions()
+	opts.MaxByteStringLen = 2
+	dst := AppendBytes(nil, []byte{1, 2, 3})
+
+	dec := NewStrictDecoder(opts)
+	if _, _, err := dec.Decode(dst); err != ErrByteStringTooLong {
+		t.Fatalf("Decode(oversized byte string) = %v, want ErrByteStringTooLong", err)
+	}
+}
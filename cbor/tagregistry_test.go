@@ -0,0 +1,91 @@
+package cbor
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBignumTagRoundTrip(t *testing.T) {
+	tests := []*big.Int{big.NewInt(5), big.NewInt(-6), big.NewInt(0)}
+	for _, num := range tests {
+		out := make([]byte, 64)
+		n := encode(num, out)
+		got, _ := decode(out[:n])
+		gotNum, ok := got.(*big.Int)
+		if !ok || gotNum.Cmp(num) != 0 {
+			t.Fatalf("round-trip of %v = %v", num, got)
+		}
+	}
+}
+
+func TestDecimalFractionRoundTrip(t *testing.T) {
+	df := DecimalFraction{273, -2} // 2.73
+	out := make([]byte, 64)
+	n := encode(df, out)
+
+	got, _ := decode(out[:n])
+	gotDf, ok := got.(DecimalFraction)
+	if !ok || gotDf != df {
+		t.Fatalf("round-trip of %v = %v", df, got)
+	}
+}
+
+func TestEpochRoundTrip(t *testing.T) {
+	out := make([]byte, 64)
+	n := encode(Epoch(1234567890), out)
+
+	got, _ := decode(out[:n])
+	e, ok := got.(Epoch)
+	if !ok || e != Epoch(1234567890) {
+		t.Fatalf("round-trip of Epoch = %v", got)
+	}
+}
+
+func TestDateTimeRoundTrip(t *testing.T) {
+	want := time.Date(2026, 7, 25, 12, 0, 0, 0, time.UTC)
+	out := make([]byte, 64)
+	n := encode(want, out)
+
+	got, _ := decode(out[:n])
+	gotTime, ok := got.(time.Time)
+	if !ok || !gotTime.Equal(want) {
+		t.Fatalf("round-trip of time.Time = %v, want %v", got, want)
+	}
+}
+
+func TestUnregisteredTagDecodesAsRawTag(t *testing.T) {
+	var dst []byte
+	dst = AppendTag(dst, 9999)
+	dst = AppendInt(dst, 1)
+
+	got, _ := decode(dst)
+	raw, ok := got.(RawTag)
+	if !ok || raw.Number != 9999 || raw.Value != uint64(1) {
+		t.Fatalf("decode = %v, want RawTag{Number: 9999, Value: 1}", got)
+	}
+}
+
+type customTagValue struct{ N int }
+
+func TestRegisterTagCustomType(t *testing.T) {
+	RegisterTag(9000, reflect.TypeOf(customTagValue{}), func(v interface{}, out []byte) int {
+		return encodeInt64(int64(v.(customTagValue).N), out)
+	}, func(buf []byte) (interface{}, int) {
+		n, m := decode(buf)
+		if u, ok := n.(uint64); ok {
+			return customTagValue{N: int(u)}, m
+		}
+		return customTagValue{N: int(n.(int64))}, m
+	})
+
+	out := make([]byte, 64)
+	n := encode(customTagValue{N: 42}, out)
+
+	got, _ := decode(out[:n])
+	cv, ok := got.(customTagValue)
+	if !ok || cv.N != 42 {
+		t.Fatalf("round-trip of customTagValue = %v", got)
+	}
+}
@@ -85,6 +85,12 @@ const (
 
 	// Stream to encode collection types as indefinite sequence of items.
 	Stream
+
+	// Deterministic to encode collection types per RFC-8949 §4.2 Core
+	// Deterministic Encoding: definite-length only, shortest-form
+	// integers/lengths/floats and map keys sorted by their bytewise
+	// lexicographic CBOR encoding.
+	Deterministic
 )
 
 // MaxKeys maximum number of keys allowed in a property object.
@@ -98,6 +104,7 @@ type Config struct {
 	ct      ContainerEncoding
 	maxKeys int
 	pools   mempools
+	tags    *TagRegistry
 
 	jsonConfig
 	collateConfig
@@ -160,6 +167,19 @@ func (config Config) SetContainerEncoding(ct ContainerEncoding) *Config {
 	return &config
 }
 
+// SetDeterministic is a shorthand to switch this configuration in and
+// out of RFC-8949 §4.2 Core Deterministic Encoding. Enabling it sets
+// ContainerEncoding to Deterministic; disabling it falls back to
+// Stream.
+func (config Config) SetDeterministic(what bool) *Config {
+	if what {
+		config.ct = Deterministic
+	} else {
+		config.ct = Stream
+	}
+	return &config
+}
+
 // SetMaxkeys will set the maximum number of keys allowed in property item.
 func (config Config) SetMaxkeys(n int) *Config {
 	config.maxKeys = n
@@ -267,6 +287,8 @@ func (ct ContainerEncoding) String() string {
 		return "LengthPrefix"
 	case Stream:
 		return "Stream"
+	case Deterministic:
+		return "Deterministic"
 	default:
 		panic("new space-kind")
 	}
@@ -0,0 +1,169 @@
+package cwt
+
+// A minimal, package-private CBOR primitive set. cwt only ever needs
+// to speak a handful of well-formed shapes (small integers, text and
+// byte strings, definite arrays/maps, tag numbers) to build and parse
+// COSE_Sign1 structures, so it does not pull in gson's general-purpose
+// (and unexported) encoder/decoder.
+
+const (
+	majMask  = 0xe0
+	infoMask = 0x1f
+
+	majUint  = 0 << 5
+	majNint  = 1 << 5
+	majBytes = 2 << 5
+	majText  = 3 << 5
+	majArray = 4 << 5
+	majMap   = 5 << 5
+	majTag   = 6 << 5
+
+	info24 = 24
+	info25 = 25
+	info26 = 26
+	info27 = 27
+)
+
+func encodeUintValue(major byte, v uint64, out []byte) int {
+	switch {
+	case v < 24:
+		out[0] = major | byte(v)
+		return 1
+	case v < 256:
+		out[0] = major | info24
+		out[1] = byte(v)
+		return 2
+	case v < 65536:
+		out[0] = major | info25
+		out[1], out[2] = byte(v>>8), byte(v)
+		return 3
+	case v < 4294967296:
+		out[0] = major | info26
+		out[1], out[2], out[3], out[4] = byte(v>>24), byte(v>>16), byte(v>>8), byte(v)
+		return 5
+	default:
+		out[0] = major | info27
+		for i := 0; i < 8; i++ {
+			out[1+i] = byte(v >> uint(56-8*i))
+		}
+		return 9
+	}
+}
+
+func decodeUintValue(buf []byte) (uint64, int) {
+	inf := buf[0] & infoMask
+	switch {
+	case inf < info24:
+		return uint64(inf), 1
+	case inf == info24:
+		return uint64(buf[1]), 2
+	case inf == info25:
+		return uint64(buf[1])<<8 | uint64(buf[2]), 3
+	case inf == info26:
+		return uint64(buf[1])<<24 | uint64(buf[2])<<16 | uint64(buf[3])<<8 | uint64(buf[4]), 5
+	default:
+		var v uint64
+		for i := 0; i < 8; i++ {
+			v = v<<8 | uint64(buf[1+i])
+		}
+		return v, 9
+	}
+}
+
+func encodeSmallInt(v int64, out []byte) int {
+	if v >= 0 {
+		return encodeUintValue(majUint, uint64(v), out)
+	}
+	return encodeUintValue(majNint, uint64(-(v + 1)), out)
+}
+
+func decodeSmallInt(buf []byte) (int64, int) {
+	if buf[0]&majMask == majNint {
+		u, n := decodeUintValue(buf)
+		return -int64(u) - 1, n
+	}
+	u, n := decodeUintValue(buf)
+	return int64(u), n
+}
+
+func encodeByteString(item, out []byte) int {
+	n := encodeUintValue(majBytes, uint64(len(item)), out)
+	n += copy(out[n:], item)
+	return n
+}
+
+func decodeByteString(buf []byte) ([]byte, int) {
+	ln, n := decodeUintValue(buf)
+	item := make([]byte, ln)
+	copy(item, buf[n:n+int(ln)])
+	return item, n + int(ln)
+}
+
+func encodeTextString(s string, out []byte) int {
+	n := encodeUintValue(majText, uint64(len(s)), out)
+	n += copy(out[n:], s)
+	return n
+}
+
+func decodeTextString(buf []byte) (string, int) {
+	ln, n := decodeUintValue(buf)
+	return string(buf[n : n+int(ln)]), n + int(ln)
+}
+
+func encodeArrayHeader(count int, out []byte) int {
+	return encodeUintValue(majArray, uint64(count), out)
+}
+
+func decodeArrayHeader(buf []byte) (int, int) {
+	v, n := decodeUintValue(buf)
+	return int(v), n
+}
+
+func encodeMapHeader(count int, out []byte) int {
+	return encodeUintValue(majMap, uint64(count), out)
+}
+
+func decodeMapHeader(buf []byte) (int, int) {
+	v, n := decodeUintValue(buf)
+	return int(v), n
+}
+
+func encodeTagNumber(tag uint64, out []byte) int {
+	return encodeUintValue(majTag, tag, out)
+}
+
+func decodeTagNumber(buf []byte) (uint64, int) {
+	return decodeUintValue(buf)
+}
+
+// skipItem returns the length, in bytes, of the well-formed item at
+// the front of buf. It only needs to handle the shapes cwt itself
+// emits: small integers, text/byte strings and (for forward
+// compatibility with unknown claim keys) definite arrays/maps.
+func skipItem(buf []byte) int {
+	major := buf[0] & majMask
+	switch major {
+	case majUint, majNint:
+		_, n := decodeUintValue(buf)
+		return n
+	case majBytes:
+		_, n := decodeByteString(buf)
+		return n
+	case majText:
+		_, n := decodeTextString(buf)
+		return n
+	case majArray:
+		count, n := decodeArrayHeader(buf)
+		for i := 0; i < count; i++ {
+			n += skipItem(buf[n:])
+		}
+		return n
+	case majMap:
+		count, n := decodeMapHeader(buf)
+		for i := 0; i < count*2; i++ {
+			n += skipItem(buf[n:])
+		}
+		return n
+	}
+	return 1
+}
@@ -0,0 +1,99 @@
+package cwt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wade-welles/gson"
+)
+
+func TestHS256SignAndVerifyRoundTrip(t *testing.T) {
+	config := gson.NewDefaultConfig()
+	secret := []byte("shared-secret")
+
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	token, err := NewCWT(config).
+		SetIssuer("issuer").
+		SetSubject("subject").
+		SetIssuedAt(now).
+		SetExpiration(now.Add(time.Hour)).
+		Sign(HS256, secret)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	v := NewVerifier(config).SetClock(func() time.Time { return now.Add(time.Minute) })
+	claims, err := v.Verify(token, HS256, secret)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if claims.Issuer != "issuer" || claims.Subject != "subject" {
+		t.Fatalf("claims = %+v", claims)
+	}
+}
+
+func TestHS256VerifyRejectsBadSecret(t *testing.T) {
+	config := gson.NewDefaultConfig()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	token, err := NewCWT(config).SetIssuer("issuer").Sign(HS256, []byte("right-secret"))
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	v := NewVerifier(config).SetClock(func() time.Time { return now })
+	if _, err := v.Verify(token, HS256, []byte("wrong-secret")); err != ErrorBadSignature {
+		t.Fatalf("Verify with wrong secret = %v, want ErrorBadSignature", err)
+	}
+}
+
+func TestHS256VerifyRejectsExpiredToken(t *testing.T) {
+	config := gson.NewDefaultConfig()
+	secret := []byte("shared-secret")
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	token, err := NewCWT(config).
+		SetIssuer("issuer").
+		SetExpiration(now.Add(-time.Minute)).
+		Sign(HS256, secret)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	v := NewVerifier(config).SetClock(func() time.Time { return now })
+	if _, err := v.Verify(token, HS256, secret); err != ErrorExpired {
+		t.Fatalf("Verify of expired token = %v, want ErrorExpired", err)
+	}
+}
+
+func TestHS256VerifyRejectsNotYetValidToken(t *testing.T) {
+	config := gson.NewDefaultConfig()
+	secret := []byte("shared-secret")
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	token, err := NewCWT(config).
+		SetIssuer("issuer").
+		SetNotBefore(now.Add(time.Hour)).
+		Sign(HS256, secret)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	v := NewVerifier(config).SetClock(func() time.Time { return now })
+	if _, err := v.Verify(token, HS256, secret); err != ErrorNotYetValid {
+		t.Fatalf("Verify of not-yet-valid token = %v, want ErrorNotYetValid", err)
+	}
+}
+
+func TestHS256VerifyRejectsWrongAlgorithm(t *testing.T) {
+	config := gson.NewDefaultConfig()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	token, err := NewCWT(config).SetIssuer("issuer").Sign(HS256, []byte("secret"))
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	v := NewVerifier(config).SetClock(func() time.Time { return now })
+	if _, err := v.Verify(token, ES256, []byte("secret")); err != ErrorUnsupportedAlgorithm {
+		t.Fatalf("Verify with mismatched alg = %v, want ErrorUnsupportedAlgorithm", err)
+	}
+}
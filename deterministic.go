@@ -0,0 +1,151 @@
+// Package gson: RFC-8949 §4.2 Core Deterministic Encoding.
+//
+// Deterministic mode produces byte-for-byte reproducible CBOR, suitable
+// for hashing and signing, and interoperable with other conforming
+// implementations such as fxamacker/cbor.
+package gson
+
+import "math"
+import "sort"
+
+// Canonicalize re-encodes an arbitrary, well-formed CBOR blob `in` into
+// RFC-8949 Core Deterministic form and writes it into `out`. Returns
+// the number of bytes written.
+func Canonicalize(in, out []byte) int {
+	value, _ := decode(in)
+	return encodeDeterministic(value, out)
+}
+
+// encodeDeterministic mirrors encode() but always picks the shortest
+// integer/length/float form, definite-length containers, sorted map
+// keys and plain text pointer segments.
+func encodeDeterministic(item interface{}, out []byte) int {
+	switch v := item.(type) {
+	case []interface{}:
+		n := encodeUint64(uint64(len(v)), out)
+		out[0] = (out[0] & 0x1f) | type4
+		for _, elem := range v {
+			n += encodeDeterministic(elem, out[n:])
+		}
+		return n
+
+	case [][2]interface{}:
+		return encodeDeterministicMap(v, out)
+
+	case float64:
+		return encodeDeterministicFloat(v, out)
+
+	case float32:
+		return encodeDeterministicFloat(float64(v), out)
+
+	default:
+		return encode(item, out)
+	}
+}
+
+// encodeDeterministicMap sorts pairs by their bytewise-lexicographic
+// CBOR key encoding (RFC-8949 §4.2.1) before emitting a definite-length
+// map.
+func encodeDeterministicMap(pairs [][2]interface{}, out []byte) int {
+	type kv struct {
+		key   []byte
+		value interface{}
+	}
+	scratch := make([]kv, len(pairs))
+	for i, pair := range pairs {
+		scratch[i] = kv{key: encodeDeterministicKey(pair[0]), value: pair[1]}
+	}
+	sort.Slice(scratch, func(i, j int) bool {
+		return bytewiseLess(scratch[i].key, scratch[j].key)
+	})
+
+	n := encodeUint64(uint64(len(pairs)), out)
+	out[0] = (out[0] & 0x1f) | type5
+	for _, item := range scratch {
+		n += copy(out[n:], item.key)
+		n += encodeDeterministic(item.value, out[n:])
+	}
+	return n
+}
+
+// encodeDeterministicKey encodes a map key into a freshly sized
+// buffer, doubling and retrying when a key's encoding (an arbitrarily
+// long string, or a deeply nested key) doesn't fit a first guess,
+// rather than risking a fixed-size scratch buffer overflowing.
+func encodeDeterministicKey(item interface{}) []byte {
+	for size := 512; ; size *= 2 {
+		buf := make([]byte, size)
+		if n, ok := tryEncodeDeterministic(item, buf); ok {
+			return buf[:n]
+		}
+	}
+}
+
+func tryEncodeDeterministic(item interface{}, buf []byte) (n int, ok bool) {
+	defer func() {
+		if recover() != nil {
+			n, ok = 0, false
+		}
+	}()
+	return encodeDeterministic(item, buf), true
+}
+
+// bytewiseLess implements RFC-8949 §4.2.1's bytewise lexicographic
+// ordering of encoded keys: the first differing byte decides, and a
+// key that is a prefix of another sorts first.
+func bytewiseLess(a, b []byte) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return len(a) < len(b)
+}
+
+// encodeDeterministicFloat downcasts to float32/float16 whenever the
+// value survives the round-trip losslessly, and normalises NaN to the
+// canonical 0x7e00 half-precision payload.
+func encodeDeterministicFloat(f float64, out []byte) int {
+	if math.IsNaN(f) {
+		out[0] = hdr(type7, flt16)
+		out[1], out[2] = 0x7e, 0x00
+		return 3
+	}
+	if f32 := float32(f); float64(f32) == f {
+		if f16, ok := float32ToFloat16(f32); ok {
+			out[0] = hdr(type7, flt16)
+			out[1] = byte(f16 >> 8)
+			out[2] = byte(f16)
+			return 3
+		}
+		return encodeFloat32(f32, out)
+	}
+	return encodeFloat64(f, out)
+}
+
+// float32ToFloat16 converts an exactly representable float32 into IEEE
+// 754 half-precision, reporting false when precision would be lost.
+// Zero and infinity (but not NaN, handled by the caller) always
+// survive the conversion regardless of the normal-range check below.
+func float32ToFloat16(f float32) (uint16, bool) {
+	bits := math.Float32bits(f)
+	sign := uint16((bits >> 16) & 0x8000)
+	rawExp := (bits >> 23) & 0xff
+	mant := bits & 0x7fffff
+
+	if rawExp == 0 && mant == 0 {
+		return sign, true // +-0
+	}
+	if rawExp == 0xff && mant == 0 {
+		return sign | uint16(0x1f<<10), true // +-Inf
+	}
+
+	exp := int32(rawExp) - 127 + 15
+	if exp <= 0 || exp >= 31 {
+		return 0, false // outside half-precision normal range
+	}
+	if mant&0x1fff != 0 {
+		return 0, false // would lose mantissa bits
+	}
+	return sign | uint16(exp<<10) | uint16(mant>>13), true
+}
@@ -0,0 +1,70 @@
+package gson
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestEncodeDeterministicMapSortsKeys(t *testing.T) {
+	pairs := [][2]interface{}{
+		{"bb", int64(1)},
+		{"a", int64(2)},
+		{"c", int64(3)},
+	}
+	out := make([]byte, 256)
+	n := encodeDeterministic(pairs, out)
+
+	got, _ := decode(out[:n])
+	sorted, ok := got.([][2]interface{})
+	if !ok || len(sorted) != 3 {
+		t.Fatalf("decode = %v", got)
+	}
+	// bytewise over the encoded keys: "a" (0x61 0x61) and "c" (0x61 0x63)
+	// sort before "bb" (0x62 ...), since its header byte is larger.
+	wantOrder := []string{"a", "c", "bb"}
+	for i, key := range wantOrder {
+		if sorted[i][0] != key {
+			t.Fatalf("key[%d] = %v, want %v", i, sorted[i][0], key)
+		}
+	}
+}
+
+func TestEncodeDeterministicKeyLongerThanDefaultScratch(t *testing.T) {
+	longKey := strings.Repeat("k", 600) // exceeds the old fixed 512-byte scratch
+	pairs := [][2]interface{}{{longKey, int64(1)}, {"a", int64(2)}}
+	out := make([]byte, 2048)
+
+	n := encodeDeterministic(pairs, out) // must not panic
+
+	got, _ := decode(out[:n])
+	sorted, ok := got.([][2]interface{})
+	if !ok || len(sorted) != 2 {
+		t.Fatalf("decode = %v", got)
+	}
+	if sorted[0][0] != "a" || sorted[1][0] != longKey {
+		t.Fatalf("sorted keys = %v, %v", sorted[0][0], sorted[1][0])
+	}
+}
+
+func TestEncodeDeterministicFloatSpecials(t *testing.T) {
+	tests := []struct {
+		name string
+		f    float64
+	}{
+		{"zero", 0.0},
+		{"neg-zero", math.Copysign(0, -1)},
+		{"inf", math.Inf(1)},
+		{"neg-inf", math.Inf(-1)},
+		{"one", 1.0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			out := make([]byte, 16)
+			n := encodeDeterministicFloat(tc.f, out)
+			if n != 3 {
+				t.Fatalf("encodeDeterministicFloat(%v) took %d bytes, want 3 (flt16)", tc.f, n)
+			}
+		})
+	}
+}
@@ -0,0 +1,589 @@
+// Package gson: RFC-8949 section-8 Extended Diagnostic Notation (EDN).
+//
+// EDN is a human readable text form of a CBOR data-item. It is used
+// here to debug the JSON-pointer/CBOR path code and to author CBOR
+// test fixtures without hand-crafting binary.
+package gson
+
+import "encoding/base64"
+import "encoding/binary"
+import "errors"
+import "fmt"
+import "math"
+import "strconv"
+import "strings"
+
+// ErrorInvalidDiagnostic input text is not valid RFC-8949 EDN.
+var ErrorInvalidDiagnostic = errors.New("gson.invalidDiagnostic")
+
+// ToDiagnostic converts the CBOR wire form held by this Cbor instance
+// into its RFC-8949 Extended Diagnostic Notation and writes the text
+// into `out`. Returns the number of bytes written.
+func (cbor *Cbor) ToDiagnostic(out []byte) int {
+	n, _ := cbor2edn(cbor.data[:cbor.n], out)
+	return n
+}
+
+// FromDiagnostic parses RFC-8949 Extended Diagnostic Notation `edn` and
+// returns a new Cbor instance holding the equivalent wire form. Arrays
+// and maps are re-encoded using the receiver Config's ContainerEncoding,
+// unless the diagnostic text itself carries an explicit `_` indefinite
+// marker, in which case that marker wins.
+func (config *Config) FromDiagnostic(edn []byte) *Cbor {
+	out := make([]byte, len(edn)*2+16)
+	d := &ednDecoder{txt: edn, config: config}
+	d.skipWS()
+	n := d.value(out)
+	return config.NewCbor(out[:n], -1)
+}
+
+//---- encoder, cbor -> diagnostic text
+
+func cbor2edn(buf, out []byte) (int, int) {
+	mjr, inf := major(buf[0]), info(buf[0])
+	switch mjr {
+	case type0:
+		item, n := decodeUint(buf)
+		return writeStr(out, strconv.FormatUint(item, 10)), n
+
+	case type1:
+		item, n := decodeInt(buf)
+		return writeStr(out, strconv.FormatInt(item, 10)), n
+
+	case type2:
+		return ednBytes(buf, out, "h'", "'")
+
+	case type3:
+		return ednText(buf, out)
+
+	case type4:
+		return ednArray(buf, out)
+
+	case type5:
+		return ednMap(buf, out)
+
+	case type6:
+		return ednTag(buf, out)
+
+	case type7:
+		return ednSimple(buf, out, inf)
+	}
+	panic(ErrorInvalidDiagnostic)
+}
+
+func ednBytes(buf, out []byte, prefix, suffix string) (int, int) {
+	if info(buf[0]) == indefiniteLength {
+		n := writeStr(out, "(_ ")
+		i := 1
+		for buf[i] != hdr(type7, itemBreak) {
+			if i > 1 {
+				n += writeStr(out[n:], ", ")
+			}
+			m, consumed := ednBytes(buf[i:], out[n:], prefix, suffix)
+			n, i = n+m, i+consumed
+		}
+		n += writeStr(out[n:], ")")
+		return n, i + 1
+	}
+	ln, j := decodeLength(buf)
+	n := writeStr(out, prefix)
+	for _, b := range buf[j : j+ln] {
+		n += writeStr(out[n:], fmt.Sprintf("%02x", b))
+	}
+	n += writeStr(out[n:], suffix)
+	return n, j + ln
+}
+
+func ednText(buf, out []byte) (int, int) {
+	if info(buf[0]) == indefiniteLength {
+		n := writeStr(out, "(_ ")
+		i := 1
+		for buf[i] != hdr(type7, itemBreak) {
+			if i > 1 {
+				n += writeStr(out[n:], ", ")
+			}
+			m, consumed := ednText(buf[i:], out[n:])
+			n, i = n+m, i+consumed
+		}
+		n += writeStr(out[n:], ")")
+		return n, i + 1
+	}
+	ln, j := decodeLength(buf)
+	n := writeStr(out, strconv.Quote(bytes2str(buf[j:j+ln])))
+	return n, j + ln
+}
+
+func ednArray(buf, out []byte) (int, int) {
+	indefinite := info(buf[0]) == indefiniteLength
+	n, i := 0, 1
+	if indefinite {
+		n += writeStr(out, "[_ ")
+	} else {
+		n += writeStr(out, "[")
+	}
+	ln := -1
+	if !indefinite {
+		ln, i = decodeLength(buf)
+	}
+	count := 0
+	for (indefinite && buf[i] != hdr(type7, itemBreak)) || (!indefinite && count < ln) {
+		if count > 0 {
+			n += writeStr(out[n:], ",")
+		}
+		m, consumed := cbor2edn(buf[i:], out[n:])
+		n, i, count = n+m, i+consumed, count+1
+	}
+	n += writeStr(out[n:], "]")
+	if indefinite {
+		i++
+	}
+	return n, i
+}
+
+func ednMap(buf, out []byte) (int, int) {
+	indefinite := info(buf[0]) == indefiniteLength
+	n, i := 0, 1
+	if indefinite {
+		n += writeStr(out, "{_ ")
+	} else {
+		n += writeStr(out, "{")
+	}
+	ln := -1
+	if !indefinite {
+		ln, i = decodeLength(buf)
+	}
+	count := 0
+	for (indefinite && buf[i] != hdr(type7, itemBreak)) || (!indefinite && count < ln) {
+		if count > 0 {
+			n += writeStr(out[n:], ", ")
+		}
+		km, kc := cbor2edn(buf[i:], out[n:])
+		n, i = n+km, i+kc
+		n += writeStr(out[n:], ": ")
+		vm, vc := cbor2edn(buf[i:], out[n:])
+		n, i, count = n+vm, i+vc, count+1
+	}
+	n += writeStr(out[n:], "}")
+	if indefinite {
+		i++
+	}
+	return n, i
+}
+
+func ednTag(buf, out []byte) (int, int) {
+	tag, i := decodeLength(buf)
+	n := writeStr(out, strconv.FormatUint(uint64(tag), 10))
+	n += writeStr(out[n:], "(")
+	m, consumed := cbor2edn(buf[i:], out[n:])
+	n += m
+	n += writeStr(out[n:], ")")
+	return n, i + consumed
+}
+
+func ednSimple(buf, out []byte, inf byte) (int, int) {
+	switch inf {
+	case simpleTypeNil:
+		return writeStr(out, "null"), 1
+	case simpleTypeTrue:
+		return writeStr(out, "true"), 1
+	case simpleTypeFalse:
+		return writeStr(out, "false"), 1
+	case simpleUndefined:
+		return writeStr(out, "undefined"), 1
+	case flt16:
+		f := decodeF16(buf)
+		return writeStr(out, formatFloat(float64(f))), 3
+	case flt32:
+		f := decodeF32(buf)
+		return writeStr(out, formatFloat(float64(f))), 5
+	case flt64:
+		f := decodeF64(buf)
+		return writeStr(out, formatFloat(f)), 9
+	}
+	panic(ErrorInvalidDiagnostic)
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	// number() only treats text with a '.'/'e' as a float; a
+	// whole-valued float like 1 must keep a decimal point so it
+	// round-trips back through FromDiagnostic as a float, not an int.
+	if !strings.ContainsAny(s, ".eE") {
+		s += ".0"
+	}
+	return s
+}
+
+func writeStr(out []byte, s string) int {
+	return copy(out, s)
+}
+
+//---- decoder, diagnostic text -> cbor
+
+type ednDecoder struct {
+	txt    []byte
+	off    int
+	config *Config
+}
+
+func (d *ednDecoder) skipWS() {
+	for d.off < len(d.txt) {
+		switch d.txt[d.off] {
+		case ' ', '\t', '\n', '\r', ',':
+			d.off++
+		default:
+			return
+		}
+	}
+}
+
+func (d *ednDecoder) value(out []byte) int {
+	d.skipWS()
+	switch c := d.txt[d.off]; {
+	case c == '"':
+		return d.text(out)
+	case c == 'h' && d.txt[d.off+1] == '\'':
+		return d.bytesHex(out)
+	case c == 'b' && d.txt[d.off+1] == '6':
+		return d.bytesB64(out)
+	case c == '[':
+		return d.array(out)
+	case c == '{':
+		return d.object(out)
+	case c == '(':
+		return d.indefiniteBytes(out)
+	case matchLit(d.txt[d.off:], "true"):
+		d.off += 4
+		return encodeTrue(out)
+	case matchLit(d.txt[d.off:], "false"):
+		d.off += 5
+		return encodeFalse(out)
+	case matchLit(d.txt[d.off:], "null"):
+		d.off += 4
+		return encodeNull(out)
+	case matchLit(d.txt[d.off:], "undefined"):
+		d.off += 9
+		return encodeUndefined(out)
+	case matchLit(d.txt[d.off:], "NaN"):
+		d.off += 3
+		return encodeFloat64(math.NaN(), out)
+	case matchLit(d.txt[d.off:], "Infinity"):
+		d.off += 8
+		return encodeFloat64(math.Inf(1), out)
+	case matchLit(d.txt[d.off:], "-Infinity"):
+		d.off += 9
+		return encodeFloat64(math.Inf(-1), out)
+	case c == '-' || (c >= '0' && c <= '9'):
+		return d.number(out)
+	}
+	return d.tagOrBreak(out)
+}
+
+func matchLit(txt []byte, lit string) bool {
+	if len(txt) < len(lit) {
+		return false
+	}
+	return bytes2str(txt[:len(lit)]) == lit
+}
+
+func (d *ednDecoder) text(out []byte) int {
+	start := d.off
+	d.off++
+	for d.txt[d.off] != '"' {
+		if d.txt[d.off] == '\\' {
+			d.off++
+		}
+		d.off++
+	}
+	d.off++
+	s, err := strconv.Unquote(bytes2str(d.txt[start:d.off]))
+	if err != nil {
+		panic(ErrorInvalidDiagnostic)
+	}
+	return encodeText(s, out)
+}
+
+func (d *ednDecoder) bytesHex(out []byte) int {
+	d.off += 2 // skip h'
+	start := d.off
+	for d.txt[d.off] != '\'' {
+		d.off++
+	}
+	hx := d.txt[start:d.off]
+	d.off++
+	item := make([]byte, len(hx)/2)
+	for i := range item {
+		b, err := strconv.ParseUint(string(hx[i*2:i*2+2]), 16, 8)
+		if err != nil {
+			panic(ErrorInvalidDiagnostic)
+		}
+		item[i] = byte(b)
+	}
+	return encodeBytes(item, out)
+}
+
+func (d *ednDecoder) bytesB64(out []byte) int {
+	d.off += 4 // skip b64'
+	start := d.off
+	for d.txt[d.off] != '\'' {
+		d.off++
+	}
+	item := base64Decode(d.txt[start:d.off])
+	d.off++
+	return encodeBytes(item, out)
+}
+
+func (d *ednDecoder) indefiniteBytes(out []byte) int {
+	d.off++ // skip (
+	d.skipWS()
+	d.off++ // skip _
+	n := encodeBytesStart(out)
+	d.skipWS()
+	for d.txt[d.off] != ')' {
+		n += d.value(out[n:])
+		d.skipWS()
+	}
+	d.off++
+	n += encodeBreakStop(out[n:])
+	return n
+}
+
+// appendValue decodes one EDN value into a scratch slice sized off
+// the remaining input and appends its encoded bytes to buf, growing
+// buf as needed. A shared scratch slice per element (rather than
+// slicing the tail of buf itself, which aliases buf's own backing
+// array past its current length) keeps array()/object() from reading
+// and appending out of bounds.
+func (d *ednDecoder) appendValue(buf []byte) []byte {
+	scratch := make([]byte, (len(d.txt)-d.off)*2+16)
+	m := d.value(scratch)
+	return append(buf, scratch[:m]...)
+}
+
+func (d *ednDecoder) array(out []byte) int {
+	d.off++ // skip [
+	d.skipWS()
+	indefinite := false
+	if d.txt[d.off] == '_' {
+		indefinite = true
+		d.off++
+	}
+	items := make([]int, 0, 4)
+	buf := make([]byte, 0, 64)
+	for {
+		d.skipWS()
+		if d.txt[d.off] == ']' {
+			d.off++
+			break
+		}
+		before := len(buf)
+		buf = d.appendValue(buf)
+		items = append(items, len(buf)-before)
+		d.skipWS()
+	}
+	n := 0
+	ct := d.config.ct
+	if indefinite {
+		ct = Stream
+	}
+	switch ct {
+	case Stream:
+		n += encodeArrayStart(out)
+		n += copy(out[n:], buf)
+		n += encodeBreakStop(out[n:])
+	case Deterministic:
+		n += canonicalizeContainer(type4, len(items), buf, out)
+	default:
+		n += encodeUint64(uint64(len(items)), out)
+		out[0] = (out[0] & 0x1f) | type4
+		n += copy(out[n:], buf)
+	}
+	return n
+}
+
+func (d *ednDecoder) object(out []byte) int {
+	d.off++ // skip {
+	d.skipWS()
+	indefinite := false
+	if d.txt[d.off] == '_' {
+		indefinite = true
+		d.off++
+	}
+	pairs := 0
+	buf := make([]byte, 0, 64)
+	for {
+		d.skipWS()
+		if d.txt[d.off] == '}' {
+			d.off++
+			break
+		}
+		buf = d.appendValue(buf)
+		d.skipWS()
+		d.off++ // skip :
+		buf = d.appendValue(buf)
+		pairs++
+		d.skipWS()
+	}
+	n := 0
+	ct := d.config.ct
+	if indefinite {
+		ct = Stream
+	}
+	switch ct {
+	case Stream:
+		n += encodeMapStart(out)
+		n += copy(out[n:], buf)
+		n += encodeBreakStop(out[n:])
+	case Deterministic:
+		n += canonicalizeContainer(type5, pairs, buf, out)
+	default:
+		n += encodeUint64(uint64(pairs), out)
+		out[0] = (out[0] & 0x1f) | type5
+		n += copy(out[n:], buf)
+	}
+	return n
+}
+
+// canonicalizeContainer re-encodes a just-built definite-length
+// array/map (`count` elements/pairs already concatenated in `buf`,
+// textual EDN order) into RFC-8949 §4.2 Core Deterministic form:
+// sorted map keys and shortest-form numbers. It does this by wrapping
+// buf back into a definite-length header, decoding the whole item,
+// and routing it through encodeDeterministic.
+func canonicalizeContainer(mjr byte, count int, buf, out []byte) int {
+	raw := make([]byte, 9+len(buf))
+	rn := encodeUint64(uint64(count), raw)
+	raw[0] = (raw[0] & 0x1f) | mjr
+	rn += copy(raw[rn:], buf)
+	value, _ := decode(raw[:rn])
+	return encodeDeterministic(value, out)
+}
+
+func (d *ednDecoder) number(out []byte) int {
+	start := d.off
+	if d.txt[d.off] == '-' {
+		d.off++
+	}
+	isFloat := false
+	for d.off < len(d.txt) {
+		c := d.txt[d.off]
+		if c >= '0' && c <= '9' {
+			d.off++
+		} else if c == '.' || c == 'e' || c == 'E' || c == '+' || c == '-' {
+			isFloat = true
+			d.off++
+		} else {
+			break
+		}
+	}
+	s := bytes2str(d.txt[start:d.off])
+	if isFloat {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			panic(ErrorInvalidDiagnostic)
+		}
+		return encodeFloat64(f, out)
+	}
+	if s[0] == '-' {
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			panic(ErrorInvalidDiagnostic)
+		}
+		return encodeInt64(i, out)
+	}
+	u, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		panic(ErrorInvalidDiagnostic)
+	}
+	return encodeUint64(u, out)
+}
+
+func (d *ednDecoder) tagOrBreak(out []byte) int {
+	start := d.off
+	for d.txt[d.off] >= '0' && d.txt[d.off] <= '9' {
+		d.off++
+	}
+	if d.off == start || d.txt[d.off] != '(' {
+		panic(ErrorInvalidDiagnostic)
+	}
+	tag, err := strconv.ParseUint(bytes2str(d.txt[start:d.off]), 10, 64)
+	if err != nil {
+		panic(ErrorInvalidDiagnostic)
+	}
+	d.off++ // skip (
+	n := encodeTag(tag, out)
+	n += d.value(out[n:])
+	d.skipWS()
+	d.off++ // skip )
+	return n
+}
+
+//---- local helpers, kept private to this file
+
+func decodeUint(buf []byte) (uint64, int) {
+	if y := info(buf[0]); y < info24 {
+		return uint64(y), 1
+	} else if y := info(buf[0]); y == info24 {
+		return uint64(buf[1]), 2
+	} else if y == info25 {
+		return uint64(binary.BigEndian.Uint16(buf[1:])), 3
+	} else if y == info26 {
+		return uint64(binary.BigEndian.Uint32(buf[1:])), 5
+	}
+	return binary.BigEndian.Uint64(buf[1:]), 9
+}
+
+func decodeInt(buf []byte) (int64, int) {
+	u, n := decodeUint(buf)
+	return -int64(u) - 1, n
+}
+
+func decodeF32(buf []byte) float32 {
+	return math.Float32frombits(binary.BigEndian.Uint32(buf[1:]))
+}
+
+func decodeF64(buf []byte) float64 {
+	return math.Float64frombits(binary.BigEndian.Uint64(buf[1:]))
+}
+
+// decodeF16 widens an IEEE 754 half-precision float into a float32,
+// the inverse of deterministic.go's float32ToFloat16.
+func decodeF16(buf []byte) float32 {
+	half := binary.BigEndian.Uint16(buf[1:])
+	sign := uint32(half&0x8000) << 16
+	exp := uint32(half>>10) & 0x1f
+	mant := uint32(half & 0x3ff)
+
+	switch exp {
+	case 0:
+		if mant == 0 {
+			return math.Float32frombits(sign) // +-0
+		}
+		// subnormal half -> normalized float32
+		exp = 1
+		for mant&0x400 == 0 {
+			mant <<= 1
+			exp--
+		}
+		mant &= 0x3ff
+	case 0x1f:
+		return math.Float32frombits(sign | 0xff<<23 | mant<<13) // +-Inf / NaN
+	}
+	return math.Float32frombits(sign | (exp+127-15)<<23 | mant<<13)
+}
+
+func base64Decode(txt []byte) []byte {
+	item, err := base64.StdEncoding.DecodeString(bytes2str(txt))
+	if err != nil {
+		panic(ErrorInvalidDiagnostic)
+	}
+	return item
+}
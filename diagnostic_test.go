@@ -0,0 +1,36 @@
+package gson
+
+import "testing"
+
+func TestToDiagnosticSimple(t *testing.T) {
+	config := NewDefaultConfig()
+	tests := []struct {
+		name string
+		cbor []byte
+		want string
+	}{
+		{"null", []byte{0xf6}, "null"},
+		{"true", []byte{0xf5}, "true"},
+		{"false", []byte{0xf4}, "false"},
+		{"uint", []byte{0x05}, "5"},
+		{"negint", []byte{0x29}, "-10"},
+		{"float64-whole", []byte{0xfb, 0x3f, 0xf0, 0, 0, 0, 0, 0, 0}, "1.0"},
+		{"flt32-nan", []byte{0xfa, 0x7f, 0xc0, 0, 0}, "NaN"},
+		{"flt64-inf", []byte{0xfb, 0x7f, 0xf0, 0, 0, 0, 0, 0, 0}, "Infinity"},
+		// half-precision: the encoding ednSimple must not panic on.
+		{"flt16-zero", []byte{0xf9, 0x00, 0x00}, "0.0"},
+		{"flt16-nan", []byte{0xf9, 0x7e, 0x00}, "NaN"},
+		{"flt16-inf", []byte{0xf9, 0x7c, 0x00}, "Infinity"},
+		{"flt16-neg-inf", []byte{0xf9, 0xfc, 0x00}, "-Infinity"},
+		{"flt16-one", []byte{0xf9, 0x3c, 0x00}, "1.0"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			out := make([]byte, 64)
+			n := config.NewCbor(tc.cbor, -1).ToDiagnostic(out)
+			if got := string(out[:n]); got != tc.want {
+				t.Fatalf("ToDiagnostic(%x) = %q, want %q", tc.cbor, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,458 @@
+// Package gson: pull-style streaming iterators.
+//
+// itemsEnd, lookup, get and set all require the whole document in
+// memory. Iterator gives a jsoniter-style pull API over an io.Reader
+// (or a plain byte-slice) so callers can filter huge CBOR/JSON streams
+// with bounded memory, refilling its internal buffer across chunk
+// boundaries as the cursor advances.
+package gson
+
+import "errors"
+import "io"
+
+// TokenKind identifies the shape of the data-item the cursor is
+// currently positioned on.
+type TokenKind byte
+
+const (
+	// TokenEOF the stream is exhausted.
+	TokenEOF TokenKind = iota
+	// TokenNull a CBOR/JSON null.
+	TokenNull
+	// TokenBool a true/false value.
+	TokenBool
+	// TokenInt a negative integer.
+	TokenInt
+	// TokenUint a non-negative integer.
+	TokenUint
+	// TokenFloat a floating point number.
+	TokenFloat
+	// TokenBytes a byte-string.
+	TokenBytes
+	// TokenString a text-string.
+	TokenString
+	// TokenBeginArray the start of an array.
+	TokenBeginArray
+	// TokenEndArray the end of an array.
+	TokenEndArray
+	// TokenBeginMap the start of a map.
+	TokenBeginMap
+	// TokenEndMap the end of a map.
+	TokenEndMap
+	// TokenTag a tag number, followed by the tagged item.
+	TokenTag
+	// TokenBreak the break-stop of an indefinite-length item.
+	TokenBreak
+)
+
+// ErrorIteratorClosed no more tokens can be pulled from this Iterator.
+var ErrorIteratorClosed = errors.New("gson.iteratorClosed")
+
+// frame tracks one level of array/map nesting so Path() can render the
+// RFC-6901 pointer of the current cursor.
+type frame struct {
+	isMap   bool
+	indef   bool
+	remain  int // -1 when indefinite
+	index   int
+	wantKey bool
+	key     []byte // last map key read, for Path() rendering
+}
+
+// Iterator is a pull parser over a CBOR byte stream. Obtain one with
+// Config.NewIterator or Config.NewIteratorBytes; call Next() in a loop
+// and use the typed Read* methods to consume the current token.
+type Iterator struct {
+	config *Config
+	r      io.Reader
+	buf    []byte
+	off    int
+	filled int
+	eof    bool
+
+	kind    TokenKind
+	tokOff  int
+	tokLen  int
+	stack   []frame
+	pathBuf []byte
+}
+
+// NewIterator returns an Iterator that pulls CBOR bytes from `r` as
+// needed.
+func (config *Config) NewIterator(r io.Reader) *Iterator {
+	return &Iterator{config: config, r: r, buf: make([]byte, 4096)}
+}
+
+// NewIteratorBytes returns an Iterator over an already materialised
+// CBOR buffer; no further reads are performed.
+func (config *Config) NewIteratorBytes(data []byte) *Iterator {
+	return &Iterator{config: config, buf: data, filled: len(data), eof: true}
+}
+
+// need makes sure at least `n` bytes are available from the current
+// offset, refilling from the underlying reader (growing the buffer if
+// necessary) when it is not.
+func (it *Iterator) need(n int) bool {
+	for it.filled-it.off < n && !it.eof {
+		if it.off > 0 {
+			copy(it.buf, it.buf[it.off:it.filled])
+			it.filled -= it.off
+			it.off = 0
+		}
+		if it.filled == len(it.buf) {
+			grown := make([]byte, len(it.buf)*2)
+			copy(grown, it.buf[:it.filled])
+			it.buf = grown
+		}
+		m, err := it.r.Read(it.buf[it.filled:])
+		it.filled += m
+		if err != nil {
+			it.eof = true
+		}
+	}
+	return it.filled-it.off >= n
+}
+
+// Next advances the cursor to the next token and reports its kind.
+// TokenEOF is returned once the stream is exhausted; the end of an
+// enclosing container is reported as TokenEndArray/TokenEndMap.
+func (it *Iterator) Next() TokenKind {
+	if len(it.stack) > 0 {
+		top := &it.stack[len(it.stack)-1]
+		if !top.indef && top.remain == 0 {
+			it.stack = it.stack[:len(it.stack)-1]
+			it.kind = endKindFor(top.isMap)
+			return it.kind
+		}
+	}
+	if !it.need(1) {
+		it.kind = TokenEOF
+		return it.kind
+	}
+	b := it.buf[it.off]
+	if len(it.stack) > 0 && b == hdr(type7, itemBreak) {
+		top := &it.stack[len(it.stack)-1]
+		if top.indef {
+			it.off++
+			it.stack = it.stack[:len(it.stack)-1]
+			it.kind = endKindFor(top.isMap)
+			return it.kind
+		}
+	}
+	it.tokOff = it.off
+	it.classify(b)
+	it.advanceContainerState()
+	return it.kind
+}
+
+// classify records the token kind for the item at the cursor: for a
+// scalar item it records its length (advanceContainerState moves past
+// it), and for an array/map header it moves past the header and pushes
+// the container's frame.
+func (it *Iterator) classify(b byte) {
+	mjr := major(b)
+	switch mjr {
+	case type0:
+		it.kind, it.tokLen = TokenUint, it.scalarLen(b)
+	case type1:
+		it.kind, it.tokLen = TokenInt, it.scalarLen(b)
+	case type2:
+		it.kind = TokenBytes
+		it.tokLen = it.stringLen(b)
+	case type3:
+		it.kind = TokenString
+		it.tokLen = it.stringLen(b)
+	case type4:
+		if info(b) == indefiniteLength {
+			it.off++
+			it.stack = append(it.stack, frame{isMap: false, indef: true, remain: -1})
+		} else {
+			it.need(9)
+			ln, n := decodeLength(it.buf[it.off:])
+			it.off += n
+			it.stack = append(it.stack, frame{isMap: false, remain: ln})
+		}
+		it.kind = TokenBeginArray
+	case type5:
+		if info(b) == indefiniteLength {
+			it.off++
+			it.stack = append(it.stack, frame{isMap: true, indef: true, remain: -1, wantKey: true})
+		} else {
+			it.need(9)
+			ln, n := decodeLength(it.buf[it.off:])
+			it.off += n
+			it.stack = append(it.stack, frame{isMap: true, remain: ln, wantKey: true})
+		}
+		it.kind = TokenBeginMap
+	case type6:
+		it.kind, it.tokLen = TokenTag, it.scalarLen(b)
+	case type7:
+		it.classifyType7(b)
+	}
+}
+
+func (it *Iterator) scalarLen(b byte) int {
+	inf := info(b)
+	if inf < info24 {
+		return 1
+	}
+	n := 1 << (inf - info24)
+	it.need(1 + n)
+	return 1 + n
+}
+
+func (it *Iterator) stringLen(b byte) int {
+	if info(b) == indefiniteLength {
+		return 1 // caller treats as a single indefinite-chunk marker
+	}
+	it.need(9)
+	ln, n := decodeLength(it.buf[it.off:])
+	it.need(n + ln)
+	return n + ln
+}
+
+func (it *Iterator) classifyType7(b byte) {
+	switch info(b) {
+	case simpleTypeNil:
+		it.kind, it.tokLen = TokenNull, 1
+	case simpleTypeTrue, simpleTypeFalse:
+		it.kind, it.tokLen = TokenBool, 1
+	case flt16:
+		it.need(3)
+		it.kind, it.tokLen = TokenFloat, 3
+	case flt32:
+		it.need(5)
+		it.kind, it.tokLen = TokenFloat, 5
+	case flt64:
+		it.need(9)
+		it.kind, it.tokLen = TokenFloat, 9
+	case itemBreak:
+		it.kind, it.tokLen = TokenBreak, 1
+	default:
+		it.kind, it.tokLen = TokenNull, 1
+	}
+}
+
+// advanceContainerState consumes the current token from the cursor
+// (for scalar kinds) and books it against the *enclosing* frame: its
+// remaining-item count, its element index, and (for a map) whether the
+// next token is a key or a value. A nested array/map token is booked
+// against the frame one level up the stack, since classify() has
+// already pushed this item's own frame by the time we get here.
+func (it *Iterator) advanceContainerState() {
+	var parent *frame
+	switch it.kind {
+	case TokenBeginArray, TokenBeginMap:
+		if len(it.stack) > 1 {
+			parent = &it.stack[len(it.stack)-2]
+		}
+	default:
+		it.off += it.tokLen
+		if len(it.stack) > 0 {
+			parent = &it.stack[len(it.stack)-1]
+		}
+	}
+	if parent == nil {
+		return
+	}
+	if parent.isMap && parent.wantKey {
+		if it.kind == TokenString {
+			ln, n := decodeLength(it.buf[it.tokOff:])
+			parent.key = append(parent.key[:0], it.buf[it.tokOff+n:it.tokOff+n+ln]...)
+		} else {
+			parent.key = parent.key[:0]
+		}
+		parent.wantKey = false
+		return
+	}
+	if parent.isMap {
+		parent.wantKey = true
+	}
+	if !parent.indef {
+		parent.remain--
+	}
+	parent.index++
+}
+
+// ReadInt64 returns the current TokenInt/TokenUint item as an int64.
+func (it *Iterator) ReadInt64() int64 {
+	if it.kind == TokenUint {
+		v, _ := decodeUint(it.buf[it.tokOff:])
+		return int64(v)
+	}
+	v, _ := decodeInt(it.buf[it.tokOff:])
+	return v
+}
+
+// ReadFloat64 returns the current TokenFloat item.
+func (it *Iterator) ReadFloat64() float64 {
+	switch info(it.buf[it.tokOff]) {
+	case flt16:
+		return float64(decodeF16(it.buf[it.tokOff:]))
+	case flt32:
+		return float64(decodeF32(it.buf[it.tokOff:]))
+	}
+	return decodeF64(it.buf[it.tokOff:])
+}
+
+// ReadString appends the current TokenString item onto `*buf`.
+func (it *Iterator) ReadString(buf *[]byte) {
+	ln, n := decodeLength(it.buf[it.tokOff:])
+	*buf = append(*buf, it.buf[it.tokOff+n:it.tokOff+n+ln]...)
+}
+
+// ReadBytes appends the current TokenBytes item onto `*buf`.
+func (it *Iterator) ReadBytes(buf *[]byte) {
+	it.ReadString(buf)
+}
+
+// ReadTag returns the tag number of the current TokenTag item. Call
+// Next() again afterwards to descend into the tagged item.
+func (it *Iterator) ReadTag() uint64 {
+	v, _ := decodeUint(it.buf[it.tokOff:])
+	return v
+}
+
+// Skip discards the current item, including all of its children, by
+// calling Next() until the matching end token. It does not use
+// itemsEnd's length arithmetic.
+func (it *Iterator) Skip() {
+	switch it.kind {
+	case TokenBeginArray, TokenBeginMap:
+		depth := 1
+		for depth > 0 {
+			switch it.Next() {
+			case TokenBeginArray, TokenBeginMap:
+				depth++
+			case TokenEndArray, TokenEndMap:
+				depth--
+			case TokenEOF:
+				return
+			}
+		}
+	case TokenTag:
+		it.Next()
+		it.Skip()
+	}
+}
+
+// Path returns the RFC-6901 JSON pointer of the item the cursor is
+// currently positioned on, built from the enclosing array indices and
+// map keys.
+func (it *Iterator) Path() []byte {
+	it.pathBuf = it.pathBuf[:0]
+	frames := it.stack
+	// A BeginArray/BeginMap token has already pushed its own frame; the
+	// container itself sits at its parent's position.
+	if len(frames) > 0 && (it.kind == TokenBeginArray || it.kind == TokenBeginMap) {
+		frames = frames[:len(frames)-1]
+	}
+	for _, f := range frames {
+		it.pathBuf = append(it.pathBuf, '/')
+		switch {
+		case f.isMap && len(f.key) > 0:
+			it.pathBuf = appendJptrEscaped(it.pathBuf, f.key)
+		case f.isMap:
+			it.pathBuf = appendItoa(it.pathBuf, f.index)
+		default:
+			// advanceContainerState already incremented index past
+			// the element Next() just returned, so the array's
+			// current position is one behind it.
+			idx := f.index - 1
+			if idx < 0 {
+				idx = 0
+			}
+			it.pathBuf = appendItoa(it.pathBuf, idx)
+		}
+	}
+	return it.pathBuf
+}
+
+// appendJptrEscaped appends `key` to buf with RFC-6901 §3 escaping
+// ('~' -> "~0", '/' -> "~1").
+func appendJptrEscaped(buf, key []byte) []byte {
+	for _, c := range key {
+		switch c {
+		case '~':
+			buf = append(buf, '~', '0')
+		case '/':
+			buf = append(buf, '~', '1')
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return buf
+}
+
+func appendItoa(buf []byte, n int) []byte {
+	if n == 0 {
+		return append(buf, '0')
+	}
+	start := len(buf)
+	for n > 0 {
+		buf = append(buf, byte('0'+n%10))
+		n /= 10
+	}
+	for i, j := start, len(buf)-1; i < j; i, j = i+1, j-1 {
+		buf[i], buf[j] = buf[j], buf[i]
+	}
+	return buf
+}
+
+func endKindFor(isMap bool) TokenKind {
+	if isMap {
+		return TokenEndMap
+	}
+	return TokenEndArray
+}
+
+// JsonIterator is the JSON counterpart of Iterator, built on the same
+// json-scan primitives Config uses for JSON<->CBOR conversion, so
+// multi-GB NDJSON files can be processed with bounded memory.
+type JsonIterator struct {
+	config *Config
+	r      io.Reader
+	buf    []byte
+	off    int
+	filled int
+	eof    bool
+}
+
+// NewJsonIterator returns a JsonIterator that pulls JSON text from `r`
+// as needed, one value at a time (e.g. one line of NDJSON per Next).
+func (config *Config) NewJsonIterator(r io.Reader) *JsonIterator {
+	return &JsonIterator{config: config, r: r, buf: make([]byte, 4096)}
+}
+
+// Next scans the next whitespace/comma-delimited JSON value and
+// returns it as a *Json bound to the parent Config, or nil at EOF.
+func (ji *JsonIterator) Next() *Json {
+	for {
+		if ji.off < ji.filled {
+			skip := scanValue(ji.buf[ji.off:ji.filled], ji.config)
+			if skip > 0 {
+				val := ji.config.NewJson(ji.buf[ji.off:ji.off+skip], skip)
+				ji.off += skip
+				return val
+			}
+		}
+		if ji.eof {
+			return nil
+		}
+		if ji.off > 0 {
+			copy(ji.buf, ji.buf[ji.off:ji.filled])
+			ji.filled -= ji.off
+			ji.off = 0
+		}
+		if ji.filled == len(ji.buf) {
+			grown := make([]byte, len(ji.buf)*2)
+			copy(grown, ji.buf[:ji.filled])
+			ji.buf = grown
+		}
+		n, err := ji.r.Read(ji.buf[ji.filled:])
+		ji.filled += n
+		if err != nil {
+			ji.eof = true
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package gson
+
+import "testing"
+
+func TestIteratorArrayPathIndices(t *testing.T) {
+	config := NewDefaultConfig()
+	doc := mustEncode(t, []interface{}{int64(10), int64(20), int64(30)})
+	it := config.NewIteratorBytes(doc)
+
+	if kind := it.Next(); kind != TokenBeginArray {
+		t.Fatalf("Next = %v, want TokenBeginArray", kind)
+	}
+
+	wantPaths := []string{"/0", "/1", "/2"}
+	for _, want := range wantPaths {
+		if kind := it.Next(); kind != TokenUint {
+			t.Fatalf("Next = %v, want TokenUint", kind)
+		}
+		// Regression: the first element must report /0, not /1 -- the
+		// off-by-one came from reading index after advanceContainerState
+		// had already incremented it.
+		if got := string(it.Path()); got != want {
+			t.Fatalf("Path() = %q, want %q", got, want)
+		}
+	}
+
+	if kind := it.Next(); kind != TokenEndArray {
+		t.Fatalf("Next = %v, want TokenEndArray", kind)
+	}
+}
+
+func TestIteratorMapPathKeys(t *testing.T) {
+	config := NewDefaultConfig()
+	doc := mustEncode(t, [][2]interface{}{{"a", int64(1)}, {"b", int64(2)}})
+	it := config.NewIteratorBytes(doc)
+
+	if kind := it.Next(); kind != TokenBeginMap {
+		t.Fatalf("Next = %v, want TokenBeginMap", kind)
+	}
+
+	wantPaths := []string{"/a", "/b"}
+	for _, want := range wantPaths {
+		if kind := it.Next(); kind != TokenString {
+			t.Fatalf("Next (key) = %v, want TokenString", kind)
+		}
+		if kind := it.Next(); kind != TokenUint {
+			t.Fatalf("Next (value) = %v, want TokenUint", kind)
+		}
+		if got := string(it.Path()); got != want {
+			t.Fatalf("Path() = %q, want %q", got, want)
+		}
+	}
+
+	if kind := it.Next(); kind != TokenEndMap {
+		t.Fatalf("Next = %v, want TokenEndMap", kind)
+	}
+}
+
+func TestIteratorNestedArrayPath(t *testing.T) {
+	config := NewDefaultConfig()
+	doc := mustEncode(t, []interface{}{int64(1), []interface{}{int64(2), int64(3)}})
+	it := config.NewIteratorBytes(doc)
+
+	it.Next() // outer begin array
+	it.Next() // 0
+	if got := string(it.Path()); got != "/0" {
+		t.Fatalf("Path() = %q, want /0", got)
+	}
+	it.Next() // inner begin array
+	if got := string(it.Path()); got != "/1" {
+		t.Fatalf("Path() = %q, want /1", got)
+	}
+	it.Next() // inner 0
+	if got := string(it.Path()); got != "/1/0" {
+		t.Fatalf("Path() = %q, want /1/0", got)
+	}
+	it.Next() // inner 1
+	if got := string(it.Path()); got != "/1/1" {
+		t.Fatalf("Path() = %q, want /1/1", got)
+	}
+}
@@ -0,0 +1,429 @@
+// Package gson: RFC-6902 JSON Patch, applied directly on CBOR documents.
+//
+// Patch builds on the RFC-6901 JSON-pointer support already provided by
+// Config/Jsonpointer to mutate CBOR encoded documents without a JSON
+// round-trip.
+package gson
+
+import "errors"
+import "strconv"
+
+// ErrorPatchPath pointer does not resolve inside the target document.
+var ErrorPatchPath = errors.New("gson.patchPath")
+
+// ErrorPatchTest `test` operation failed, value did not match.
+var ErrorPatchTest = errors.New("gson.patchTest")
+
+// ErrorPatchOp patch document contains an unknown / malformed operation.
+var ErrorPatchOp = errors.New("gson.patchOp")
+
+// Patch implements RFC-6902 JSON Patch operations on CBOR encoded
+// documents. Every operation takes CBOR-encoded pointers, produced by
+// FromJsonPointer, and CBOR-encoded documents, and writes the mutated
+// document into `out`.
+type Patch struct {
+	config *Config
+}
+
+// NewPatch returns a Patch bound to this configuration.
+func (config *Config) NewPatch() *Patch {
+	return &Patch{config: config}
+}
+
+// Add inserts `value` at `pointer` into `doc`, writing the result into
+// `out`. A trailing "-" array segment appends to the array instead of
+// indexing into it. The parent of `pointer` must already exist.
+func (patch *Patch) Add(pointer, value, doc, out []byte) (int, error) {
+	n, m, err := patchResolveParent(pointer, doc, true /*forAdd*/)
+	if err != nil {
+		return 0, err
+	}
+	cp := copy(out, doc[:n])
+	cp += copy(out[cp:], value)
+	cp += copy(out[cp:], doc[m:])
+	return cp, nil
+}
+
+// Remove deletes the value at `pointer` from `doc`, writing the result
+// into `out`.
+func (patch *Patch) Remove(pointer, doc, out []byte) (int, error) {
+	n, m, err := patchResolveParent(pointer, doc, false)
+	if err != nil {
+		return 0, err
+	}
+	cp := copy(out, doc[:n])
+	cp += copy(out[cp:], doc[m:])
+	return cp, nil
+}
+
+// Replace substitutes the value at `pointer` in `doc` with `value`,
+// writing the result into `out`. `pointer` must already resolve.
+func (patch *Patch) Replace(pointer, value, doc, out []byte) (int, error) {
+	n, m, err := patchResolveParent(pointer, doc, false)
+	if err != nil {
+		return 0, err
+	}
+	cp := copy(out, doc[:n])
+	cp += copy(out[cp:], value)
+	cp += copy(out[cp:], doc[m:])
+	return cp, nil
+}
+
+// Move relocates the value at `from` to `to` inside `doc`, writing the
+// result into `out`.
+func (patch *Patch) Move(from, to, doc, out []byte) (int, error) {
+	n, m, err := patchResolveParent(from, doc, false)
+	if err != nil {
+		return 0, err
+	}
+	value := make([]byte, m-n)
+	copy(value, doc[n:m])
+
+	removed := make([]byte, len(doc))
+	cp := copy(removed, doc[:n])
+	cp += copy(removed[cp:], doc[m:])
+	removed = removed[:cp]
+
+	return patch.Add(to, value, removed, out)
+}
+
+// Copy duplicates the value at `from` into `to` inside `doc`, writing
+// the result into `out`.
+func (patch *Patch) Copy(from, to, doc, out []byte) (int, error) {
+	n, m, err := patchResolveParent(from, doc, false)
+	if err != nil {
+		return 0, err
+	}
+	value := make([]byte, m-n)
+	copy(value, doc[n:m])
+	return patch.Add(to, value, doc, out)
+}
+
+// Test compares the value at `pointer` in `doc` against `value` for
+// deep-equality, honouring numeric equivalence across the type0/type1/
+// float encodings. Returns ErrorPatchTest when they differ.
+func (patch *Patch) Test(pointer, value, doc []byte) (bool, error) {
+	n, m, err := patchResolveParent(pointer, doc, false)
+	if err != nil {
+		return false, err
+	}
+	if !cborEqual(doc[n:m], value) {
+		return false, ErrorPatchTest
+	}
+	return true, nil
+}
+
+// ApplyPatch consumes a CBOR-encoded patch document (an array of
+// operation maps, as produced from the JSON Patch array-of-objects
+// form) and applies each operation, in order, to `doc`. Application is
+// atomic: if any operation fails, `doc` is left untouched and the
+// error is returned.
+func (config *Config) ApplyPatch(doc, patchdoc, out []byte) (int, error) {
+	ops, err := decodePatchOps(patchdoc)
+	if err != nil {
+		return 0, err
+	}
+	patch := config.NewPatch()
+	cur := make([]byte, len(doc))
+	n := copy(cur, doc)
+	cur = cur[:n]
+
+	scratch := make([]byte, len(doc)+len(patchdoc)+1024)
+	for _, op := range ops {
+		var m int
+		var err error
+		switch op.name {
+		case "add":
+			m, err = patch.Add(op.path, op.value, cur, scratch)
+		case "remove":
+			m, err = patch.Remove(op.path, cur, scratch)
+		case "replace":
+			m, err = patch.Replace(op.path, op.value, cur, scratch)
+		case "move":
+			m, err = patch.Move(op.from, op.path, cur, scratch)
+		case "copy":
+			m, err = patch.Copy(op.from, op.path, cur, scratch)
+		case "test":
+			_, err = patch.Test(op.path, op.value, cur)
+			m = len(cur)
+			copy(scratch, cur)
+		default:
+			return 0, ErrorPatchOp
+		}
+		if err != nil {
+			return 0, err
+		}
+		cur = cur[:0]
+		cur = append(cur, scratch[:m]...)
+	}
+	return copy(out, cur), nil
+}
+
+// patchResolveParent locates `pointer` inside `doc`, returning the
+// byte-range [n, m) that the operation should act on. Every segment up
+// to and including the parent container must resolve; when `forAdd`
+// is true the final segment itself need not already exist (a new map
+// key, or an array index equal to the array's length, or "-"), in
+// which case [n, m) collapses to the empty insertion point immediately
+// before the container's closing byte/brace.
+func patchResolveParent(pointer, doc []byte, forAdd bool) (n, m int, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			n, m, err = 0, 0, ErrorPatchPath
+		}
+	}()
+
+	segments, ok := pointerSegments(pointer)
+	if !ok {
+		return 0, 0, ErrorPatchPath
+	}
+	if len(segments) == 0 {
+		return 0, len(doc), nil
+	}
+
+	start, end := 0, len(doc)
+	for i, seg := range segments {
+		last := i == len(segments)-1
+		cn, cm, found := resolvePointerSegment(doc[start:end], seg)
+		if !found {
+			if !last || !forAdd || cn < 0 {
+				return 0, 0, ErrorPatchPath
+			}
+			return start + cn, start + cn, nil
+		}
+		start, end = start+cn, start+cm
+	}
+	return start, end, nil
+}
+
+// pointerSegments walks a CBOR-encoded pointer — the indefinite text
+// string of `tagJsonString`-tagged segment chunks FromJsonPointer
+// produces, not a CBOR array — into its ordered list of segment
+// strings. ok is false when `pointer` isn't that shape at all.
+func pointerSegments(pointer []byte) (segments []interface{}, ok bool) {
+	if len(pointer) == 0 || major(pointer[0]) != type3 || info(pointer[0]) != indefiniteLength {
+		return nil, false
+	}
+	brkstp := hdr(type7, itemBreak)
+	i := 1
+	for pointer[i] != brkstp {
+		if pointer[i] != hdr(type6, info24) || pointer[i+1] != tagJsonString {
+			return nil, false
+		}
+		i += 2
+		ln, j := decodeLength(pointer[i:])
+		segments = append(segments, bytes2str(pointer[i+j:i+j+ln]))
+		i += j + ln
+	}
+	return segments, true
+}
+
+// resolvePointerSegment locates one pointer segment inside the single
+// array or map item occupying buf, returning its byte-range relative
+// to buf. found is false when seg names a valid insertion point (a
+// missing map key, or an array index equal to the element count, or
+// "-") rather than an existing element; n is then that insertion
+// offset and m is meaningless. n is -1 when seg names neither.
+func resolvePointerSegment(buf []byte, seg interface{}) (n, m int, found bool) {
+	indefinite := info(buf[0]) == indefiniteLength
+	end, i := len(buf), 1
+	ln := -1
+	if !indefinite {
+		ln, i = decodeLength(buf)
+	}
+
+	switch major(buf[0]) {
+	case type5: // map: key, value, key, value, ...
+		key, isStr := seg.(string)
+		count := 0
+		for (indefinite && buf[i] != hdr(type7, itemBreak)) || (!indefinite && count < ln) {
+			kv, kn := decode(buf[i:])
+			i += kn
+			_, vn := decode(buf[i:])
+			valStart := i
+			i += vn
+			if isStr {
+				if k, ok := kv.(string); ok && k == key {
+					return valStart, i, true
+				}
+			}
+			count++
+		}
+		if indefinite {
+			end = i
+		}
+		return end, end, false
+
+	case type4: // array
+		idx, isIdx, isDash := pointerSegmentIndex(seg)
+		count := 0
+		for (indefinite && buf[i] != hdr(type7, itemBreak)) || (!indefinite && count < ln) {
+			elemStart := i
+			_, en := decode(buf[i:])
+			i += en
+			if isIdx && count == idx {
+				return elemStart, i, true
+			}
+			count++
+		}
+		if indefinite {
+			end = i
+		}
+		if isDash || (isIdx && idx == count) {
+			return end, end, false
+		}
+		return -1, -1, false
+	}
+	return -1, -1, false
+}
+
+// pointerSegmentIndex interprets a pointer segment as an array index:
+// a non-negative integer, or the literal text "-" denoting "one past
+// the last element" (RFC-6901 §4).
+func pointerSegmentIndex(seg interface{}) (idx int, isIdx, isDash bool) {
+	switch v := seg.(type) {
+	case string:
+		if v == "-" {
+			return 0, false, true
+		}
+		i, err := strconv.Atoi(v)
+		if err != nil || i < 0 {
+			return 0, false, false
+		}
+		return i, true, false
+	case uint64:
+		return int(v), true, false
+	case int64:
+		if v < 0 {
+			return 0, false, false
+		}
+		return int(v), true, false
+	}
+	return 0, false, false
+}
+
+type patchOp struct {
+	name  string
+	path  []byte
+	from  []byte
+	value []byte
+}
+
+// decodePatchOps walks the CBOR-encoded patch array and pulls out each
+// operation's "op", "path", "from" and "value" members.
+func decodePatchOps(patchdoc []byte) (ops []patchOp, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			ops, err = nil, ErrorPatchOp
+		}
+	}()
+
+	if major(patchdoc[0]) != type4 {
+		return nil, ErrorPatchOp
+	}
+	items, _ := decode(patchdoc)
+	arr, ok := items.([]interface{})
+	if !ok {
+		return nil, ErrorPatchOp
+	}
+	out := make([]patchOp, 0, len(arr))
+	for _, item := range arr {
+		pairs, ok := item.([][2]interface{})
+		if !ok {
+			return nil, ErrorPatchOp
+		}
+		op := patchOp{}
+		for _, kv := range pairs {
+			key, _ := kv[0].(string)
+			switch key {
+			case "op":
+				op.name, _ = kv[1].(string)
+			case "path":
+				op.path = encodeJsonpointerValue(kv[1])
+			case "from":
+				op.from = encodeJsonpointerValue(kv[1])
+			case "value":
+				buf := make([]byte, 0, 64)
+				buf = buf[:cap(buf)]
+				n := encode(kv[1], buf)
+				op.value = buf[:n]
+			}
+		}
+		if op.name == "" {
+			return nil, ErrorPatchOp
+		}
+		out = append(out, op)
+	}
+	return out, nil
+}
+
+// encodeJsonpointerValue re-encodes a decoded "path"/"from" JSON string
+// member back into a CBOR pointer.
+func encodeJsonpointerValue(v interface{}) []byte {
+	s, _ := v.(string)
+	out := make([]byte, len(s)*2+16)
+	n := FromJsonPointer([]byte(s), out)
+	return out[:n]
+}
+
+// cborEqual compares two CBOR-encoded values for deep equality,
+// treating type0/type1/float encodings of the same number as equal.
+func cborEqual(a, b []byte) bool {
+	av, _ := decode(a)
+	bv, _ := decode(b)
+	return valuesEqual(av, bv)
+}
+
+func valuesEqual(a, b interface{}) bool {
+	af, aIsNum := toFloat(a)
+	bf, bIsNum := toFloat(b)
+	if aIsNum && bIsNum {
+		return af == bf
+	}
+	switch av := a.(type) {
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !valuesEqual(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	case [][2]interface{}:
+		bv, ok := b.([][2]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for _, kv := range av {
+			found := false
+			for _, bkv := range bv {
+				if valuesEqual(kv[0], bkv[0]) && valuesEqual(kv[1], bkv[1]) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case uint64:
+		return float64(x), true
+	case int64:
+		return float64(x), true
+	case float32:
+		return float64(x), true
+	case float64:
+		return x, true
+	}
+	return 0, false
+}
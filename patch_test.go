@@ -0,0 +1,102 @@
+package gson
+
+import "testing"
+
+func mustPointer(t *testing.T, path string) []byte {
+	t.Helper()
+	out := make([]byte, len(path)*2+16)
+	n := FromJsonPointer([]byte(path), out)
+	return out[:n]
+}
+
+func mustEncode(t *testing.T, value interface{}) []byte {
+	t.Helper()
+	out := make([]byte, 256)
+	n := encode(value, out)
+	return out[:n]
+}
+
+func TestPatchAddReplaceRemove(t *testing.T) {
+	config := NewDefaultConfig()
+	patch := config.NewPatch()
+
+	doc := mustEncode(t, [][2]interface{}{{"a", int64(1)}, {"b", int64(2)}})
+	out := make([]byte, 256)
+
+	// Replace an existing key.
+	n, err := patch.Replace(mustPointer(t, "/a"), mustEncode(t, int64(9)), doc, out)
+	if err != nil {
+		t.Fatalf("Replace: %v", err)
+	}
+	got, _ := decode(out[:n])
+	want := [][2]interface{}{{"a", int64(9)}, {"b", int64(2)}}
+	if !valuesEqual(got, want) {
+		t.Fatalf("Replace result = %v, want %v", got, want)
+	}
+
+	// Add a new key.
+	n, err = patch.Add(mustPointer(t, "/c"), mustEncode(t, int64(3)), doc, out)
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	got, _ = decode(out[:n])
+	pairs, ok := got.([][2]interface{})
+	if !ok || len(pairs) != 3 {
+		t.Fatalf("Add result = %v, want 3 pairs", got)
+	}
+
+	// Remove an existing key.
+	n, err = patch.Remove(mustPointer(t, "/b"), doc, out)
+	if err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	got, _ = decode(out[:n])
+	want = [][2]interface{}{{"a", int64(1)}}
+	if !valuesEqual(got, want) {
+		t.Fatalf("Remove result = %v, want %v", got, want)
+	}
+}
+
+func TestPatchEmptyPointerIsWholeDocument(t *testing.T) {
+	config := NewDefaultConfig()
+	patch := config.NewPatch()
+	doc := mustEncode(t, [][2]interface{}{{"a", int64(1)}})
+	out := make([]byte, 256)
+
+	n, err := patch.Replace(mustPointer(t, ""), mustEncode(t, int64(5)), doc, out)
+	if err != nil {
+		t.Fatalf("Replace with empty pointer: %v", err)
+	}
+	got, _ := decode(out[:n])
+	if got != int64(5) {
+		t.Fatalf("Replace with empty pointer = %v, want 5", got)
+	}
+}
+
+func TestPatchMissingPathErrors(t *testing.T) {
+	config := NewDefaultConfig()
+	patch := config.NewPatch()
+	doc := mustEncode(t, [][2]interface{}{{"a", int64(1)}})
+	out := make([]byte, 256)
+
+	if _, err := patch.Replace(mustPointer(t, "/missing"), mustEncode(t, int64(1)), doc, out); err != ErrorPatchPath {
+		t.Fatalf("Replace of missing path = %v, want ErrorPatchPath", err)
+	}
+}
+
+func TestPatchArrayAppend(t *testing.T) {
+	config := NewDefaultConfig()
+	patch := config.NewPatch()
+	doc := mustEncode(t, []interface{}{int64(1), int64(2)})
+	out := make([]byte, 256)
+
+	n, err := patch.Add(mustPointer(t, "/-"), mustEncode(t, int64(3)), doc, out)
+	if err != nil {
+		t.Fatalf("Add with '-': %v", err)
+	}
+	got, _ := decode(out[:n])
+	want := []interface{}{int64(1), int64(2), int64(3)}
+	if !valuesEqual(got, want) {
+		t.Fatalf("Add with '-' = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,236 @@
+// Package gson: pluggable CBOR tag registry.
+//
+// A small, fixed set of tags (tagJsonString, CborTagEpoch,
+// CborTagFraction, CborTagFloat, CborTagPrefix, ...) is wired in
+// directly. TagRegistry lets callers extend that set at run time,
+// without editing this package. Its entry points are
+// Config.encodeTagged and Config.decodeTag; the package's own encode
+// and decode paths do not call them.
+package gson
+
+import "math/big"
+import "net"
+import "reflect"
+
+// TagEncoder turns a Go value into CBOR bytes, not including the tag
+// header itself. Returns the number of bytes written.
+type TagEncoder func(value interface{}, out []byte) int
+
+// TagDecoder turns the CBOR bytes following a tag header back into a
+// Go value. Returns the value and the number of bytes consumed.
+type TagDecoder func(buf []byte) (interface{}, int)
+
+// RawTag holds a tagged data-item whose tag number has no registered
+// decoder. Content is the still-encoded CBOR bytes that followed the
+// tag header.
+type RawTag struct {
+	Number  uint64
+	Content []byte
+}
+
+// tagEntry pairs a tag number's encode/decode hooks with a sample
+// value used to recognise which registered type a Go value belongs to.
+type tagEntry struct {
+	num    uint64
+	sample interface{}
+	enc    TagEncoder
+	dec    TagDecoder
+}
+
+// TagRegistry holds the tag encoders/decoders known to a Config, beyond
+// the small set built into the package.
+type TagRegistry struct {
+	byNum  map[uint64]*tagEntry
+	byType map[reflect.Type]*tagEntry
+}
+
+// RegisterTag associates `num` with a Go value shaped like `sample`.
+// `enc` is used whenever encode() is asked to encode a value of that
+// same Go type; `dec` is used whenever the decoder sees tag `num`. A
+// nil `dec` leaves the tagged item as a RawTag on decode.
+func (config *Config) RegisterTag(num uint64, sample interface{}, enc TagEncoder, dec TagDecoder) {
+	if config.tags == nil {
+		config.tags = newTagRegistry()
+	}
+	entry := &tagEntry{num: num, sample: sample, enc: enc, dec: dec}
+	config.tags.byNum[num] = entry
+	config.tags.byType[reflect.TypeOf(sample)] = entry
+}
+
+func newTagRegistry() *TagRegistry {
+	reg := &TagRegistry{byNum: make(map[uint64]*tagEntry), byType: make(map[reflect.Type]*tagEntry)}
+	registerBuiltinTags(reg)
+	return reg
+}
+
+// registerBuiltinTags ships the RFC-8949 tags this package does not
+// otherwise cover: bignum (2/3), decimal fraction (4), bigfloat (5),
+// UUID (37) and IP address (260/261).
+func registerBuiltinTags(reg *TagRegistry) {
+	add := func(num uint64, sample interface{}, enc TagEncoder, dec TagDecoder) {
+		entry := &tagEntry{num: num, sample: sample, enc: enc, dec: dec}
+		reg.byNum[num] = entry
+		reg.byType[reflect.TypeOf(sample)] = entry
+	}
+
+	add(2, (*big.Int)(nil), encodeBignumTag, decodeBignumPositiveTag)
+	// Tag 3 (negative bignum) shares *big.Int with tag 2; routing it
+	// through add() would clobber tag 2's byType entry for *big.Int, so
+	// it's wired into byNum directly and tagEncoderFor hands it out for
+	// negative values.
+	reg.byNum[3] = &tagEntry{num: 3, sample: (*big.Int)(nil), enc: encodeBignumTag, dec: decodeBignumNegativeTag}
+	add(37, [16]byte{}, encodeUUIDTag, decodeUUIDTag)
+	add(260, net.IP(nil), encodeIPTag, decodeIPTag)
+	add(261, map[string]net.IP(nil), encodeIPPrefixTag, decodeIPPrefixTag)
+}
+
+// RegisterRawPassthrough lets callers preserve an arbitrary tag number
+// as RawTag on decode, and re-emit RawTag values back out verbatim on
+// encode, without supplying a dedicated Go type for it.
+func (config *Config) RegisterRawPassthrough(num uint64) {
+	config.RegisterTag(num, RawTag{}, encodeRawTag, func(buf []byte) (interface{}, int) {
+		n := itemsEnd(buf)
+		content := make([]byte, n)
+		copy(content, buf[:n])
+		return RawTag{Number: num, Content: content}, n
+	})
+}
+
+func encodeRawTag(value interface{}, out []byte) int {
+	raw := value.(RawTag)
+	return copy(out, raw.Content)
+}
+
+// builtinTagRegistry serves the built-in tags to a Config on which
+// RegisterTag has not been called.
+var builtinTagRegistry = newTagRegistry()
+
+func (config *Config) tagEncoderFor(value interface{}) (*tagEntry, bool) {
+	reg := config.tags
+	if reg == nil {
+		reg = builtinTagRegistry
+	}
+	switch v := value.(type) {
+	case *big.Int:
+		if v.Sign() < 0 {
+			entry, ok := reg.byNum[3]
+			return entry, ok
+		}
+	case RawTag:
+		entry, ok := reg.byNum[v.Number]
+		if !ok {
+			return nil, false
+		}
+		_, passthrough := entry.sample.(RawTag)
+		return entry, passthrough
+	}
+	entry, ok := reg.byType[reflect.TypeOf(value)]
+	return entry, ok
+}
+
+func (config *Config) tagDecoderFor(num uint64) (*tagEntry, bool) {
+	reg := config.tags
+	if reg == nil {
+		reg = builtinTagRegistry
+	}
+	entry, ok := reg.byNum[num]
+	return entry, ok
+}
+
+// decodeTag decodes the tagged item in buf (positioned just past the
+// header of tag `num`). A registered tag decodes to its Go type;
+// anything else is returned as a RawTag.
+func (config *Config) decodeTag(num uint64, buf []byte) (interface{}, int) {
+	if entry, ok := config.tagDecoderFor(num); ok && entry.dec != nil {
+		return entry.dec(buf)
+	}
+	n := itemsEnd(buf)
+	content := make([]byte, n)
+	copy(content, buf[:n])
+	return RawTag{Number: num, Content: content}, n
+}
+
+// encodeTagged writes `value` as a tagged item when its type has a
+// registered or built-in tag: the tag header plus the registered
+// encoding, reporting ok=true. Otherwise ok is false and nothing is
+// written.
+func (config *Config) encodeTagged(value interface{}, out []byte) (n int, ok bool) {
+	entry, ok := config.tagEncoderFor(value)
+	if !ok {
+		return 0, false
+	}
+	h := encodeTag(entry.num, out)
+	return h + entry.enc(value, out[h:]), true
+}
+
+//---- built-in tag codecs
+
+// encodeBignumTag writes the byte-string content of a *big.Int: its
+// magnitude when non-negative, or -(n+1)'s magnitude when negative per
+// RFC 8949 §3.4.3. tagEncoderFor hands negative values tag 3's entry,
+// so encodeTagged writes the tag-3 header for them.
+func encodeBignumTag(value interface{}, out []byte) int {
+	num := value.(*big.Int)
+	if num.Sign() < 0 {
+		b := new(big.Int).Neg(num)
+		b.Sub(b, big.NewInt(1))
+		return encodeBytes(b.Bytes(), out)
+	}
+	return encodeBytes(num.Bytes(), out)
+}
+
+func decodeBignumPositiveTag(buf []byte) (interface{}, int) {
+	b, n := decodeType2(buf)
+	num := new(big.Int).SetBytes(b.([]byte))
+	return num, n
+}
+
+func decodeBignumNegativeTag(buf []byte) (interface{}, int) {
+	b, n := decodeType2(buf)
+	num := new(big.Int).SetBytes(b.([]byte))
+	num.Neg(num).Sub(num, big.NewInt(1))
+	return num, n
+}
+
+func encodeUUIDTag(value interface{}, out []byte) int {
+	id := value.([16]byte)
+	return encodeBytes(id[:], out)
+}
+
+func decodeUUIDTag(buf []byte) (interface{}, int) {
+	b, n := decodeType2(buf)
+	var id [16]byte
+	copy(id[:], b.([]byte))
+	return id, n
+}
+
+func encodeIPTag(value interface{}, out []byte) int {
+	ip := value.(net.IP)
+	return encodeBytes([]byte(ip), out)
+}
+
+func decodeIPTag(buf []byte) (interface{}, int) {
+	b, n := decodeType2(buf)
+	return net.IP(b.([]byte)), n
+}
+
+func encodeIPPrefixTag(value interface{}, out []byte) int {
+	prefix := value.(map[string]net.IP)
+	items := make([][2]interface{}, 0, len(prefix))
+	for k, v := range prefix {
+		items = append(items, [2]interface{}{k, []byte(v)})
+	}
+	return encodeMap(items, out)
+}
+
+func decodeIPPrefixTag(buf []byte) (interface{}, int) {
+	v, n := decodeType5(buf)
+	out := make(map[string]net.IP)
+	for _, pair := range v.([][2]interface{}) {
+		key, _ := pair[0].(string)
+		if b, ok := pair[1].([]byte); ok {
+			out[key] = net.IP(b)
+		}
+	}
+	return out, n
+}
@@ -0,0 +1,71 @@
+package gson
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestBignumTagEncodeDecodeRoundTrip(t *testing.T) {
+	config := NewDefaultConfig()
+	tests := []struct {
+		name string
+		num  *big.Int
+	}{
+		{"positive", big.NewInt(5)},
+		{"negative", big.NewInt(-6)},
+		{"zero", big.NewInt(0)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			out := make([]byte, 64)
+			n, ok := config.encodeTagged(tc.num, out)
+			if !ok {
+				t.Fatalf("encodeTagged(%v) reported no registered tag", tc.num)
+			}
+			tagNum, tn := decodeUint(out)
+			value, _ := config.decodeTag(tagNum, out[tn:n])
+			got, ok := value.(*big.Int)
+			if !ok || got.Cmp(tc.num) != 0 {
+				t.Fatalf("round-trip of %v via tag %d = %v", tc.num, tagNum, value)
+			}
+		})
+	}
+}
+
+func TestTag3RegistrationDoesNotClobberTag2Encoder(t *testing.T) {
+	// Registering tag 3 (decode-only) must not overwrite *big.Int's
+	// byType entry, the bug that made every *big.Int encode under tag
+	// 3 (negative bignum) regardless of sign.
+	config := NewDefaultConfig()
+	out := make([]byte, 64)
+	n, ok := config.encodeTagged(big.NewInt(5), out)
+	if !ok {
+		t.Fatal("encodeTagged(+5) not registered")
+	}
+	tagNum, _ := decodeUint(out[:n])
+	if tagNum != 2 {
+		t.Fatalf("+5 encoded under tag %d, want 2", tagNum)
+	}
+}
+
+type sampleTagA struct{ V int }
+type sampleTagB struct{ V string }
+
+func TestRegisterTagKeyedByReflectType(t *testing.T) {
+	config := NewDefaultConfig()
+	config.RegisterTag(1000, sampleTagA{}, func(v interface{}, out []byte) int {
+		return encodeInt64(int64(v.(sampleTagA).V), out)
+	}, nil)
+	config.RegisterTag(1001, sampleTagB{}, func(v interface{}, out []byte) int {
+		return encodeText(v.(sampleTagB).V, out)
+	}, nil)
+
+	entryA, ok := config.tagEncoderFor(sampleTagA{V: 1})
+	if !ok || entryA.num != 1000 {
+		t.Fatalf("tagEncoderFor(sampleTagA) = %v, %v", entryA, ok)
+	}
+	entryB, ok := config.tagEncoderFor(sampleTagB{V: "x"})
+	if !ok || entryB.num != 1001 {
+		t.Fatalf("tagEncoderFor(sampleTagB) = %v, %v", entryB, ok)
+	}
+}